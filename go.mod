@@ -4,7 +4,11 @@ go 1.24.0
 
 toolchain go1.24.12
 
-require github.com/jackc/pgx/v5 v5.8.0
+require (
+	github.com/jackc/pgx/v5 v5.8.0
+	go.opentelemetry.io/otel v1.38.0
+	go.opentelemetry.io/otel/trace v1.38.0
+)
 
 require (
 	github.com/jackc/pgpassfile v1.0.0 // indirect
@@ -0,0 +1,81 @@
+package tx
+
+import (
+	"context"
+	"slices"
+)
+
+// -----------------------------
+// PostgreSQL Advisory Locks
+// -----------------------------
+
+// WithPostgresAdvisoryLock executes the given function within a PostgreSQL
+// transaction holding the transaction-scoped advisory lock key.
+//
+// The lock is acquired with pg_advisory_xact_lock, waiting until it is
+// available, and is released automatically when the transaction commits
+// or rolls back. If a transaction already exists in the context, the lock
+// is taken in it and held until that transaction ends.
+func (r *BaseRepo) WithPostgresAdvisoryLock(
+	ctx context.Context,
+	key int64,
+	fn func(ctx context.Context) error,
+) error {
+
+	return r.WithPostgresDBTx(ctx, func(ctx context.Context) error {
+		if _, err := r.PostgresQueryExecutor(ctx).ExecContext(ctx, "SELECT pg_advisory_xact_lock($1)", key); err != nil {
+			return err
+		}
+		return fn(ctx)
+	})
+}
+
+// WithPostgresAdvisoryLocks is like WithPostgresAdvisoryLock but holds
+// the advisory locks of all keys.
+//
+// The locks are acquired one by one in ascending key order, whatever the
+// order of keys, so that concurrent callers locking overlapping sets
+// always take them in the same order and cannot deadlock on each other.
+// Duplicate keys are locked once; keys itself is not modified.
+func (r *BaseRepo) WithPostgresAdvisoryLocks(
+	ctx context.Context,
+	keys []int64,
+	fn func(ctx context.Context) error,
+) error {
+
+	sorted := slices.Compact(slices.Sorted(slices.Values(keys)))
+
+	return r.WithPostgresDBTx(ctx, func(ctx context.Context) error {
+		for _, key := range sorted {
+			if _, err := r.PostgresQueryExecutor(ctx).ExecContext(ctx, "SELECT pg_advisory_xact_lock($1)", key); err != nil {
+				return err
+			}
+		}
+		return fn(ctx)
+	})
+}
+
+// WithPostgresTryAdvisoryLock is like WithPostgresAdvisoryLock but does
+// not wait for the lock.
+//
+// If the lock is held elsewhere, fn is not called and false is returned
+// with a nil error. Otherwise it reports true along with fn's outcome.
+func (r *BaseRepo) WithPostgresTryAdvisoryLock(
+	ctx context.Context,
+	key int64,
+	fn func(ctx context.Context) error,
+) (bool, error) {
+
+	var acquired bool
+	err := r.WithPostgresDBTx(ctx, func(ctx context.Context) error {
+		row := r.PostgresQueryExecutor(ctx).QueryRowContext(ctx, "SELECT pg_try_advisory_xact_lock($1)", key)
+		if err := row.Scan(&acquired); err != nil {
+			return err
+		}
+		if !acquired {
+			return nil
+		}
+		return fn(ctx)
+	})
+	return acquired, err
+}
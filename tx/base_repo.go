@@ -3,10 +3,17 @@ package tx
 import (
 	"context"
 	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // contextKey is a private type to avoid context key collisions.
@@ -15,15 +22,137 @@ type contextKey string
 const (
 	txKey          contextKey = "postgres_tx"
 	timescaleTxKey contextKey = "timescale_tx"
+	mysqlTxKey     contextKey = "mysql_tx"
+	sqliteTxKey    contextKey = "sqlite_tx"
 )
 
-// BaseRepo provides transaction management for PostgreSQL and TimescaleDB.
+// txKeys holds the context keys a BaseRepo stores its transactions
+// under, one per backend.
+type txKeys struct {
+	postgres  contextKey
+	timescale contextKey
+	mysql     contextKey
+	sqlite    contextKey
+}
+
+// newTxKeys returns the context keys for the given namespace. The empty
+// namespace yields the keys shared by all repositories without one.
+func newTxKeys(namespace string) txKeys {
+	keys := txKeys{
+		postgres:  txKey,
+		timescale: timescaleTxKey,
+		mysql:     mysqlTxKey,
+		sqlite:    sqliteTxKey,
+	}
+	if namespace == "" {
+		return keys
+	}
+
+	prefix := namespace + ":"
+	keys.postgres = contextKey(prefix) + keys.postgres
+	keys.timescale = contextKey(prefix) + keys.timescale
+	keys.mysql = contextKey(prefix) + keys.mysql
+	keys.sqlite = contextKey(prefix) + keys.sqlite
+	return keys
+}
+
+// Backend identifies the database a transaction runs against.
+type Backend string
+
+const (
+	BackendPostgres  Backend = "postgres"
+	BackendTimescale Backend = "timescale"
+	BackendMySQL     Backend = "mysql"
+	BackendSQLite    Backend = "sqlite"
+)
+
+// SQLExecutor is the query interface shared by *sql.DB and *sql.Tx.
+type SQLExecutor interface {
+	ExecContext(context.Context, string, ...any) (sql.Result, error)
+	QueryContext(context.Context, string, ...any) (*sql.Rows, error)
+	QueryRowContext(context.Context, string, ...any) *sql.Row
+}
+
+// sqlBackend describes a database reached through database/sql, the
+// context key its transactions are stored under and its default options.
+type sqlBackend struct {
+	backend Backend
+	db      *sql.DB
+	key     contextKey
+
+	// defaultOpts is used to start transactions requested without
+	// options.
+	defaultOpts *sql.TxOptions
+
+	// driver, if set, begins the transactions instead of db, see
+	// NewBaseRepoWithDrivers.
+	driver SQLDriver
+}
+
+// configured reports whether the backend has a database to run on.
+func (b sqlBackend) configured() bool {
+	return b.db != nil || b.driver != nil
+}
+
+// sqlTx is the value stored in the context for an active transaction on
+// a database/sql backend. The options it was started with are kept so
+// nested calls can detect conflicting requests.
+type sqlTx struct {
+	tx     SQLTx
+	opts   sql.TxOptions
+	scope  *txScope
+	stmts  *stmtCache
+	failed *failedStatement
+}
+
+// timescaleTx is the value stored in the context for an active
+// TimescaleDB transaction.
+type timescaleTx struct {
+	tx    pgx.Tx
+	opts  pgx.TxOptions
+	scope *txScope
+}
+
+// BaseRepo provides transaction management for PostgreSQL and TimescaleDB,
+// and optionally MySQL and SQLite.
 //
 // It enables context-based transaction propagation, allowing multiple
 // repositories to share the same transaction across layers.
 type BaseRepo struct {
-	postgresDB  *sql.DB
-	timescaleDB *pgxpool.Pool
+	postgresDB        *sql.DB
+	postgresDriver    SQLDriver
+	timescaleDB       *pgxpool.Pool
+	mysqlDB           *sql.DB
+	sqliteDB          *sql.DB
+	replicas          []*replica
+	replicaCooldown   time.Duration
+	keys              txKeys
+	postgresOpts      *sql.TxOptions
+	timescaleOpts     pgx.TxOptions
+	postgresRetry     *RetryConfig
+	timescaleRetry    *RetryConfig
+	beginTimeout      time.Duration
+	rollbackTimeout   time.Duration
+	commitGracePeriod time.Duration
+	slowTxThreshold   time.Duration
+	maxTxLifetime     time.Duration
+	labelAppName      bool
+	recoverPanics     bool
+	cacheStatements   bool
+	logFailedStmts    bool
+	rollbackOnCancel  bool
+	strict            *openTxs
+	warnCrossBackend  bool
+	clock             Clock
+	tracer            trace.Tracer
+	metrics           TxMetrics
+	logger            *slog.Logger
+	hooks             []TxHook
+	setups            []SetupFunc
+	active            activeTxs
+	shuttingDown      atomic.Bool
+	idempotencyTable  string
+	cockroachAttempts int
 }
 
 // Compile-time assertion to ensure BaseRepo implements TxRepository.
@@ -33,11 +162,47 @@ var _ TxRepository = (*BaseRepo)(nil)
 //
 // postgresDB   → *sql.DB for PostgreSQL
 // timescaleDB  → *pgxpool.Pool for TimescaleDB
-func NewBaseRepo(postgresDB *sql.DB, timescaleDB *pgxpool.Pool) *BaseRepo {
-	return &BaseRepo{
-		postgresDB:  postgresDB,
-		timescaleDB: timescaleDB,
+// opts         → optional behaviour such as tracing, metrics and logging
+func NewBaseRepo(postgresDB *sql.DB, timescaleDB *pgxpool.Pool, opts ...Option) *BaseRepo {
+	r := &BaseRepo{
+		postgresDB:        postgresDB,
+		timescaleDB:       timescaleDB,
+		keys:              newTxKeys(""),
+		tracer:            defaultTracer(),
+		metrics:           noopMetrics{},
+		logger:            discardLogger,
+		idempotencyTable:  defaultIdempotencyTable,
+		replicaCooldown:   defaultReplicaCooldown,
+		rollbackTimeout:   defaultRollbackTimeout,
+		clock:             realClock{},
+		cockroachAttempts: defaultCockroachAttempts,
 	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// -----------------------------
+// Database Handles
+// -----------------------------
+
+// PostgresDB returns the PostgreSQL *sql.DB the repository was created
+// with, for operations the repository does not cover.
+//
+// Statements run on it directly never take part in a transaction from
+// the context.
+func (r *BaseRepo) PostgresDB() *sql.DB {
+	return r.postgresDB
+}
+
+// TimescalePool returns the TimescaleDB *pgxpool.Pool the repository was
+// created with, for operations the repository does not cover.
+//
+// Statements run on it directly never take part in a transaction from
+// the context.
+func (r *BaseRepo) TimescalePool() *pgxpool.Pool {
+	return r.timescaleDB
 }
 
 // -----------------------------
@@ -48,37 +213,366 @@ func NewBaseRepo(postgresDB *sql.DB, timescaleDB *pgxpool.Pool) *BaseRepo {
 //
 // If a transaction already exists in the context, it will be reused.
 // The transaction is automatically committed on success or rolled
-// back on error or panic.
+// back on error or panic. If the context is done by the time fn returns,
+// the transaction is rolled back and ctx.Err() is returned instead of
+// attempting the commit.
 func (r *BaseRepo) WithTimescaleDBTx(
 	ctx context.Context,
 	fn func(ctx context.Context) error,
 ) error {
+	return r.WithTimescaleDBTxOpts(ctx, pgx.TxOptions{}, fn)
+}
+
+// WithTimescaleDBTxOpts executes the given function within a TimescaleDB
+// transaction started with the given options.
+//
+// opts is forwarded to BeginTx, so IsoLevel, AccessMode and
+// DeferrableMode can be chosen per call; the zero value selects the
+// options set with WithDefaultTimescaleTxOptions, or the server defaults.
+//
+// If a transaction already exists in the context, it will be reused and
+// opts is not applied to it. A request for an isolation level other than
+// the active transaction's is rejected with ErrNestedTxOptionsConflict
+// instead of being silently ignored; access and deferrable modes of the
+// active transaction are kept as they are.
+//
+// txOpts, such as StatementTimeout, configure the transaction once it
+// has begun; they are ignored when a transaction is reused.
+//
+// A new transaction is retried according to WithDefaultTimescaleRetry,
+// if set, unless a transaction of another backend is open in ctx.
+func (r *BaseRepo) WithTimescaleDBTxOpts(
+	ctx context.Context,
+	opts pgx.TxOptions,
+	fn func(ctx context.Context) error,
+	txOpts ...TxOption,
+) error {
+
+	if r.timescaleRetry != nil && outsideTx(ctx) {
+		return r.retry(ctx, BackendTimescale, *r.timescaleRetry, func(ctx context.Context) error {
+			return r.withTimescaleTx(ctx, opts, fn, txOpts)
+		})
+	}
+	return r.withTimescaleTx(ctx, opts, fn, txOpts)
+}
+
+// WithTimescaleDBTxFunc executes fn within a TimescaleDB transaction
+// started with opts and hands it the pgx.Tx, like pgx.BeginTxFunc. It is
+// WithTimescaleDBTxOpts with the transaction passed explicitly: the
+// context given to fn carries the transaction too, and reuse, commit and
+// rollback behave the same.
+func (r *BaseRepo) WithTimescaleDBTxFunc(
+	ctx context.Context,
+	opts pgx.TxOptions,
+	fn func(ctx context.Context, tx pgx.Tx) error,
+) error {
+
+	return r.WithTimescaleDBTxOpts(ctx, opts, func(ctx context.Context) error {
+		tx, _ := r.GetTimescaleTx(ctx)
+		return fn(ctx, tx)
+	})
+}
+
+// withTimescaleTx implements the TimescaleDB transaction lifecycle of
+// WithTimescaleDBTxOpts, without retries.
+func (r *BaseRepo) withTimescaleTx(
+	ctx context.Context,
+	opts pgx.TxOptions,
+	fn func(ctx context.Context) error,
+	txOpts []TxOption,
+) (err error) {
 
 	// Reuse existing transaction if present
-	if _, ok := r.GetTimescaleTx(ctx); ok {
-		return fn(ctx)
+	if active, ok := r.timescaleTxFromContext(ctx); ok {
+		if err := checkTimescaleTxOptions(active.opts, opts); err != nil {
+			return err
+		}
+		return fn(withOutermost(ctx, false))
 	}
 
-	tx, err := r.timescaleDB.Begin(ctx)
+	if r.timescaleDB == nil {
+		return ErrTimescaleNotConfigured
+	}
+
+	if err := r.enter(BackendTimescale); err != nil {
+		return err
+	}
+	defer r.leave(BackendTimescale)
+
+	if opts == (pgx.TxOptions{}) {
+		opts = r.timescaleOpts
+	}
+
+	ctx, run := r.startRun(ctx, BackendTimescale)
+
+	ctx, cancelLifetime := r.lifetimeContext(ctx)
+	defer cancelLifetime()
+	defer func() { err = lifetimeError(ctx, err) }()
+
+	tx, err := r.beginTimescale(ctx, opts)
 	if err != nil {
+		run.beginFailed(err)
 		return err
 	}
 
-	txCtx := context.WithValue(ctx, timescaleTxKey, tx)
+	txCtx, scope := newScopeContext(ctx, r.clock)
+
+	stopWatch := func() {}
+	if r.rollbackOnCancel || r.maxTxLifetime > 0 {
+		stopWatch = watchCancel(ctx, func() {
+			if !r.rollbackOnCancel && !lifetimeExceeded(ctx) {
+				return
+			}
+			// fn may still be using the connection: only the statement in
+			// progress is cancelled, and the rollback is left to the
+			// helper once fn returns.
+			cancelCtx, cancel := r.rollbackContext(ctx)
+			defer cancel()
+			_ = tx.Conn().PgConn().CancelRequest(cancelCtx)
+		})
+	}
+
+	// finished disarms the rollback on panic once the transaction has
+	// ended: a panic in a callback or hook then propagates as is.
+	var finished bool
+	rollback := func(cause error) error {
+		finished = true
+		stopWatch()
+		rbCtx, cancel := r.rollbackContext(ctx)
+		defer cancel()
+		rbErr := rollbackErr(tx.Rollback(rbCtx))
+		run.rolledBack(cause, rbErr)
+		scope.rolledBack()
+		return rollbackResult(cause, rbErr)
+	}
 
+	// Installed before the hooks run, so a panic in one rolls back too.
 	defer func() {
 		if p := recover(); p != nil {
-			_ = tx.Rollback(ctx)
-			panic(p)
+			if finished {
+				panic(p)
+			}
+			err = rollback(r.recovered(run, p))
+			if !r.recoverPanics {
+				panic(p)
+			}
 		}
 	}()
 
+	run.begun()
+	defer r.trackOpen(BackendTimescale)()
+
+	txCtx = context.WithValue(txCtx, r.keys.timescale, &timescaleTx{tx: tx, opts: opts, scope: scope})
+	txCtx = withOutermost(txCtx, true)
+	txCtx = context.WithValue(txCtx, loggerKey, run.txLogger())
+	run.bind(txCtx)
+
+	cfg := r.labelConfig(newTxConfig(txOpts), BackendTimescale, run.label)
+	if err := cfg.apply(txCtx, func(ctx context.Context, query string) error {
+		_, err := tx.Exec(ctx, query)
+		return err
+	}); err != nil {
+		return rollback(err)
+	}
+
+	if err := r.setUp(txCtx, BackendTimescale, func(ctx context.Context, query string, args ...any) error {
+		_, err := tx.Exec(ctx, query, args...)
+		return err
+	}); err != nil {
+		return rollback(err)
+	}
+
 	if err := fn(txCtx); err != nil {
-		_ = tx.Rollback(ctx)
+		return rollback(err)
+	}
+
+	if err := scope.runBeforeCommit(txCtx); err != nil {
+		return rollback(err)
+	}
+
+	if scope.isRollbackOnly() {
+		return rollback(ErrRollbackOnly)
+	}
+
+	// Do not commit work whose context is already done
+	if err := txCtx.Err(); err != nil {
+		return rollback(err)
+	}
+
+	stopWatch()
+	commitCtx, cancel := r.commitContext(ctx)
+	defer cancel()
+	finished = true
+	if err := tx.Commit(commitCtx); err != nil {
+		err = commitError(ctx, err)
+		run.rolledBack(err, nil)
+		scope.rolledBack()
 		return err
 	}
 
-	return tx.Commit(ctx)
+	run.committed()
+	scope.committed()
+	return nil
+}
+
+// recovered logs a panic recovered inside a transaction together with
+// the stack that raised it, and returns it as the rollback cause. Unless
+// RecoverPanics is enabled, the caller re-panics with the original value.
+func (r *BaseRepo) recovered(run *txRun, p any) error {
+	perr := newPanicError(p)
+	run.log(slog.LevelError, "panic in transaction",
+		slog.Any("panic", p),
+		slog.String("stack", string(perr.Stack)),
+	)
+	return perr
+}
+
+// labelConfig derives application_name from the transaction's label
+// when LabelApplicationName is enabled and none was set explicitly. Only
+// PostgreSQL-compatible backends support it.
+func (r *BaseRepo) labelConfig(cfg txConfig, backend Backend, label string) txConfig {
+	if !r.labelAppName || label == "" || cfg.applicationName != "" {
+		return cfg
+	}
+	if backend == BackendPostgres || backend == BackendTimescale {
+		cfg.applicationName = label
+	}
+	return cfg
+}
+
+// rollbackContext returns the context a TimescaleDB rollback is sent
+// with. It keeps the values of ctx but not its cancellation, so that the
+// rollback still reaches the server after the caller has gone away, and
+// is bounded by the rollback timeout instead.
+func (r *BaseRepo) rollbackContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.WithoutCancel(ctx), r.rollbackTimeout)
+}
+
+// commitContext returns the context a TimescaleDB commit is sent with:
+// ctx itself, or with a commit grace period, a context detached from
+// ctx's cancellation and bounded by the grace period instead.
+func (r *BaseRepo) commitContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if r.commitGracePeriod <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(context.WithoutCancel(ctx), r.commitGracePeriod)
+}
+
+// watchCancel calls onDone as soon as ctx is done. The returned stop
+// function must be called before the transaction is finished: once it
+// returns, onDone is either prevented from running or has completed, so
+// the two never race. stop may be called more than once.
+func watchCancel(ctx context.Context, onDone func()) (stop func()) {
+	done := make(chan struct{})
+	stopAfter := context.AfterFunc(ctx, func() {
+		defer close(done)
+		onDone()
+	})
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			if !stopAfter() {
+				<-done
+			}
+		})
+	}
+}
+
+// beginTimescale starts a TimescaleDB transaction, bounding connection
+// acquisition and BEGIN by the configured begin timeout.
+func (r *BaseRepo) beginTimescale(ctx context.Context, opts pgx.TxOptions) (pgx.Tx, error) {
+	if r.beginTimeout <= 0 {
+		return r.timescaleDB.BeginTx(ctx, opts)
+	}
+
+	beginCtx, cancel := context.WithTimeout(ctx, r.beginTimeout)
+	defer cancel()
+
+	tx, err := r.timescaleDB.BeginTx(beginCtx, opts)
+	if err != nil {
+		return nil, r.beginError(ctx, beginCtx, err)
+	}
+	return tx, nil
+}
+
+// beginError reports err as ErrBeginTimeout when it was caused by the
+// begin timeout rather than by the caller's context.
+func (r *BaseRepo) beginError(parent, beginCtx context.Context, err error) error {
+	if parent.Err() == nil && errors.Is(beginCtx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("%w after %s: %w", ErrBeginTimeout, r.beginTimeout, err)
+	}
+	return err
+}
+
+// commitError explains a commit that failed because the transaction was
+// already finished, typically by user code calling Commit or Rollback on
+// the transaction returned by GetTxFromContext or GetTimescaleTx, or
+// because an earlier statement failed and aborted it. A transaction the
+// driver closed because ctx was cancelled is reported as is.
+func commitError(ctx context.Context, err error) error {
+	if sqlState(err) == sqlStateInFailedTransaction || errors.Is(err, pgx.ErrTxCommitRollback) {
+		return fmt.Errorf("%w: %w", ErrTxAborted, err)
+	}
+	if ctx.Err() == nil && (errors.Is(err, sql.ErrTxDone) || errors.Is(err, pgx.ErrTxClosed)) {
+		return fmt.Errorf("%w: %w", ErrTxClosedPrematurely, err)
+	}
+	return err
+}
+
+// rollbackResult returns the error reported for a transaction rolled
+// back because of cause. A deliberate rollback requested with
+// ErrRollbackOnly is not reported as a failure.
+func rollbackResult(cause, rbErr error) error {
+	if rbErr == nil && errors.Is(cause, ErrRollbackOnly) {
+		return nil
+	}
+	return errors.Join(cause, rbErr)
+}
+
+// rollbackErr filters out the error reported when rolling back a
+// transaction that is already finished, such as one the database/sql
+// package rolled back after its context was cancelled.
+func rollbackErr(err error) error {
+	if errors.Is(err, sql.ErrTxDone) || errors.Is(err, pgx.ErrTxClosed) {
+		return nil
+	}
+	return err
+}
+
+// lifetimeContext bounds ctx by the lifetime configured with
+// MaxTxLifetime, if any.
+func (r *BaseRepo) lifetimeContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if r.maxTxLifetime <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeoutCause(ctx, r.maxTxLifetime, ErrTxLifetimeExceeded)
+}
+
+// lifetimeExceeded reports whether ctx, as returned by lifetimeContext,
+// is done because the transaction's lifetime expired.
+func lifetimeExceeded(ctx context.Context) bool {
+	return errors.Is(context.Cause(ctx), ErrTxLifetimeExceeded)
+}
+
+// lifetimeError marks err, returned by a transaction helper, as caused by
+// the expiry of the transaction's lifetime when ctx reports it.
+func lifetimeError(ctx context.Context, err error) error {
+	if err == nil || !lifetimeExceeded(ctx) || errors.Is(err, ErrTxLifetimeExceeded) {
+		return err
+	}
+	return fmt.Errorf("%w: %w", ErrTxLifetimeExceeded, err)
+}
+
+// checkTimescaleTxOptions verifies that the requested options can be
+// satisfied by an already active transaction.
+func checkTimescaleTxOptions(active, requested pgx.TxOptions) error {
+	if requested.IsoLevel == "" || requested.IsoLevel == active.IsoLevel {
+		return nil
+	}
+	return fmt.Errorf(
+		"%w: requested isolation level %q, active transaction isolation level %q",
+		ErrNestedTxOptionsConflict, requested.IsoLevel, active.IsoLevel,
+	)
 }
 
 // -----------------------------
@@ -89,37 +583,334 @@ func (r *BaseRepo) WithTimescaleDBTx(
 //
 // If a transaction already exists in the context, it will be reused.
 // The transaction is automatically committed on success or rolled
-// back on error or panic.
+// back on error or panic. If the context is done by the time fn returns,
+// the transaction is rolled back and ctx.Err() is returned instead of
+// attempting the commit.
 func (r *BaseRepo) WithPostgresDBTx(
 	ctx context.Context,
 	fn func(ctx context.Context) error,
 ) error {
+	return r.WithPostgresDBTxOpts(ctx, nil, fn)
+}
+
+// WithPostgresDBTxOpts executes the given function within a PostgreSQL
+// transaction started with the given options.
+//
+// opts is forwarded to BeginTx; nil selects the options set with
+// WithDefaultPostgresTxOptions, or the driver defaults.
+// If a transaction already exists in the context, it will be reused,
+// unless opts requests an isolation level different from the one the
+// active transaction runs at, in which case ErrNestedTxOptionsConflict
+// is returned and fn is not called.
+//
+// txOpts, such as StatementTimeout, configure the transaction once it
+// has begun; they are ignored when a transaction is reused.
+//
+// A new transaction is retried according to WithDefaultPostgresRetry,
+// if set, unless a transaction of another backend is open in ctx.
+func (r *BaseRepo) WithPostgresDBTxOpts(
+	ctx context.Context,
+	opts *sql.TxOptions,
+	fn func(ctx context.Context) error,
+	txOpts ...TxOption,
+) error {
+
+	cfg := newTxConfig(txOpts)
+	if r.postgresRetry != nil && outsideTx(ctx) {
+		return r.retry(ctx, BackendPostgres, *r.postgresRetry, func(ctx context.Context) error {
+			return r.withSQLTx(ctx, r.postgres(), opts, cfg, fn)
+		})
+	}
+	return r.withSQLTx(ctx, r.postgres(), opts, cfg, fn)
+}
+
+// postgres returns the PostgreSQL database/sql backend.
+func (r *BaseRepo) postgres() sqlBackend {
+	return sqlBackend{backend: BackendPostgres, db: r.postgresDB, key: r.keys.postgres, defaultOpts: r.postgresOpts, driver: r.postgresDriver}
+}
+
+// withSQLTx implements the transaction lifecycle shared by the
+// database/sql backends. cfg is applied only to newly started
+// transactions.
+func (r *BaseRepo) withSQLTx(
+	ctx context.Context,
+	b sqlBackend,
+	opts *sql.TxOptions,
+	cfg txConfig,
+	fn func(ctx context.Context) error,
+) (err error) {
 
 	// Reuse existing transaction if present
-	if _, ok := r.GetTxFromContext(ctx); ok {
-		return fn(ctx)
+	if active, ok := sqlTxFromContext(ctx, b.key); ok {
+		if err := checkSQLTxOptions(active.opts, opts); err != nil {
+			return err
+		}
+		return fn(withOutermost(ctx, false))
+	}
+
+	if !b.configured() {
+		return errNotConfigured(b.backend)
+	}
+
+	if err := r.enter(b.backend); err != nil {
+		return err
+	}
+	defer r.leave(b.backend)
+
+	if opts == nil {
+		opts = b.defaultOpts
 	}
 
-	tx, err := r.postgresDB.BeginTx(ctx, nil)
+	ctx, run := r.startRun(ctx, b.backend)
+
+	ctx, cancelLifetime := r.lifetimeContext(ctx)
+	defer cancelLifetime()
+	defer func() { err = lifetimeError(ctx, err) }()
+
+	tx, release, err := r.beginSQL(ctx, b, opts)
 	if err != nil {
+		run.beginFailed(err)
 		return err
 	}
+	defer release()
 
-	txCtx := context.WithValue(ctx, txKey, tx)
+	txCtx, scope := newScopeContext(ctx, r.clock)
+
+	// finished disarms the rollback on panic once the transaction has
+	// ended: a panic in a callback or hook then propagates as is.
+	var finished bool
+	rollback := func(cause error) error {
+		finished = true
+		rbErr := rollbackErr(tx.Rollback())
+		run.rolledBack(cause, rbErr)
+		scope.rolledBack()
+		return rollbackResult(cause, rbErr)
+	}
 
+	// Installed before the hooks run, so a panic in one rolls back too.
 	defer func() {
 		if p := recover(); p != nil {
-			_ = tx.Rollback()
-			panic(p)
+			if finished {
+				panic(p)
+			}
+			err = rollback(r.recovered(run, p))
+			if !r.recoverPanics {
+				panic(p)
+			}
 		}
 	}()
 
+	run.begun()
+	defer r.trackOpen(b.backend)()
+
+	state := &sqlTx{tx: tx, scope: scope}
+	if r.cacheStatements {
+		state.stmts = newStmtCache()
+	}
+	if r.logFailedStmts {
+		state.failed = new(failedStatement)
+		run.failed = state.failed
+	}
+	if opts != nil {
+		state.opts = *opts
+	}
+	txCtx = context.WithValue(txCtx, b.key, state)
+	txCtx = withOutermost(txCtx, true)
+	txCtx = context.WithValue(txCtx, loggerKey, run.txLogger())
+	run.bind(txCtx)
+
+	cfg = r.labelConfig(cfg, b.backend, run.label)
+	if err := cfg.apply(txCtx, func(ctx context.Context, query string) error {
+		_, err := tx.ExecContext(ctx, query)
+		return err
+	}); err != nil {
+		return rollback(err)
+	}
+
+	if err := r.setUp(txCtx, b.backend, func(ctx context.Context, query string, args ...any) error {
+		_, err := tx.ExecContext(ctx, query, args...)
+		return err
+	}); err != nil {
+		return rollback(err)
+	}
+
 	if err := fn(txCtx); err != nil {
-		_ = tx.Rollback()
+		return rollback(err)
+	}
+
+	if err := scope.runBeforeCommit(txCtx); err != nil {
+		return rollback(err)
+	}
+
+	if scope.isRollbackOnly() {
+		return rollback(ErrRollbackOnly)
+	}
+
+	// Do not commit work whose context is already done
+	if err := txCtx.Err(); err != nil {
+		return rollback(err)
+	}
+
+	finished = true
+	if err := tx.Commit(); err != nil {
+		err = commitError(ctx, err)
+		run.rolledBack(err, nil)
+		scope.rolledBack()
 		return err
 	}
 
-	return tx.Commit()
+	run.committed()
+	scope.committed()
+	return nil
+}
+
+// beginSQL starts a transaction on b. When a begin timeout is
+// configured, only acquiring the connection is bounded by it: a
+// database/sql transaction is tied to the context it begins on for its
+// whole lifetime. release must be called once the transaction is done.
+func (r *BaseRepo) beginSQL(
+	ctx context.Context,
+	b sqlBackend,
+	opts *sql.TxOptions,
+) (SQLTx, func(), error) {
+
+	if b.driver != nil {
+		return b.beginDriver(ctx, opts)
+	}
+
+	if r.beginTimeout <= 0 {
+		tx, err := b.db.BeginTx(ctx, opts)
+		if err != nil {
+			return nil, nil, err
+		}
+		return tx, func() {}, nil
+	}
+
+	beginCtx, cancel := context.WithTimeout(ctx, r.beginTimeout)
+	defer cancel()
+
+	conn, err := b.db.Conn(beginCtx)
+	if err != nil {
+		return nil, nil, r.beginError(ctx, beginCtx, err)
+	}
+
+	tx, err := conn.BeginTx(ctx, opts)
+	if err != nil {
+		_ = conn.Close()
+		return nil, nil, err
+	}
+	return tx, func() { _ = conn.Close() }, nil
+}
+
+// checkSQLTxOptions verifies that the requested options can be
+// satisfied by an already active transaction.
+func checkSQLTxOptions(active sql.TxOptions, requested *sql.TxOptions) error {
+	if requested == nil || requested.Isolation == sql.LevelDefault {
+		return nil
+	}
+	if requested.Isolation != active.Isolation {
+		return fmt.Errorf(
+			"%w: requested isolation level %s, active transaction isolation level %s",
+			ErrNestedTxOptionsConflict, requested.Isolation, active.Isolation,
+		)
+	}
+	return nil
+}
+
+// -----------------------------
+// Read-Only Transactions
+// -----------------------------
+
+// WithPostgresDBReadTx executes the given function within a read-only
+// PostgreSQL transaction.
+//
+// Any write attempted inside fn fails with an error from the server.
+// If a transaction already exists in the context, it is reused as is.
+func (r *BaseRepo) WithPostgresDBReadTx(
+	ctx context.Context,
+	fn func(ctx context.Context) error,
+) error {
+	return r.WithPostgresDBTxOpts(ctx, &sql.TxOptions{ReadOnly: true}, fn)
+}
+
+// WithTimescaleDBReadTx executes the given function within a read-only
+// TimescaleDB transaction.
+//
+// Any write attempted inside fn fails with an error from the server.
+// If a transaction already exists in the context, it is reused as is.
+func (r *BaseRepo) WithTimescaleDBReadTx(
+	ctx context.Context,
+	fn func(ctx context.Context) error,
+) error {
+	return r.WithTimescaleDBTxOpts(ctx, pgx.TxOptions{AccessMode: pgx.ReadOnly}, fn)
+}
+
+// -----------------------------
+// Transaction Timeouts
+// -----------------------------
+
+// WithPostgresDBTxTimeout executes the given function within a PostgreSQL
+// transaction bounded by the timeout d.
+//
+// The transaction is started on a context that expires after d, so it is
+// rolled back once the deadline passes, and SET LOCAL statement_timeout
+// is issued so the server enforces the same bound on every statement.
+// When the timeout is hit, the returned error wraps
+// context.DeadlineExceeded; other failures are returned as usual.
+//
+// If a transaction already exists in the context, it is reused and only
+// fn's context is bounded by d.
+func (r *BaseRepo) WithPostgresDBTxTimeout(
+	ctx context.Context,
+	d time.Duration,
+	fn func(ctx context.Context) error,
+) error {
+
+	timeoutCtx, cancel := context.WithTimeout(ctx, d)
+	defer cancel()
+
+	err := r.withSQLTx(timeoutCtx, r.postgres(), nil, txConfig{statementTimeout: d}, fn)
+	return timeoutError(ctx, timeoutCtx, d, err)
+}
+
+// WithTimescaleDBTxTimeout executes the given function within a
+// TimescaleDB transaction bounded by the timeout d.
+//
+// It mirrors WithPostgresDBTxTimeout: the transaction begins on a context
+// that expires after d, so it is rolled back once the deadline passes,
+// and SET LOCAL statement_timeout is issued so the server enforces the
+// same bound on every statement. When the timeout is hit, the returned
+// error wraps context.DeadlineExceeded.
+//
+// If a transaction already exists in the context, it is reused and only
+// fn's context is bounded by d.
+func (r *BaseRepo) WithTimescaleDBTxTimeout(
+	ctx context.Context,
+	d time.Duration,
+	fn func(ctx context.Context) error,
+) error {
+
+	timeoutCtx, cancel := context.WithTimeout(ctx, d)
+	defer cancel()
+
+	err := r.WithTimescaleDBTxOpts(timeoutCtx, pgx.TxOptions{}, fn, StatementTimeout(d))
+	return timeoutError(ctx, timeoutCtx, d, err)
+}
+
+// timeoutError marks err as a timeout when it was caused by the deadline
+// of timeoutCtx or by the server-side statement_timeout derived from it,
+// rather than by the parent context or an ordinary query failure.
+func timeoutError(parent, timeoutCtx context.Context, d time.Duration, err error) error {
+	if err == nil || parent.Err() != nil {
+		return err
+	}
+	if timeoutCtx.Err() == nil && sqlState(err) != sqlStateQueryCanceled {
+		return err
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return fmt.Errorf("tx: transaction timed out after %s: %w", d, err)
+	}
+	return fmt.Errorf("tx: transaction timed out after %s: %w: %w", d, context.DeadlineExceeded, err)
 }
 
 // -----------------------------
@@ -127,15 +918,62 @@ func (r *BaseRepo) WithPostgresDBTx(
 // -----------------------------
 
 // GetTxFromContext retrieves a PostgreSQL transaction from the context.
+//
+// Transactions begun by a SQLDriver are only returned if they are a
+// *sql.Tx; PostgresQueryExecutor works with all of them.
 func (r *BaseRepo) GetTxFromContext(ctx context.Context) (*sql.Tx, bool) {
-	tx, ok := ctx.Value(txKey).(*sql.Tx)
+	active, ok := sqlTxFromContext(ctx, r.keys.postgres)
+	if !ok {
+		return nil, false
+	}
+	tx, ok := active.tx.(*sql.Tx)
 	return tx, ok
 }
 
+// postgresTx retrieves the PostgreSQL transaction from the context,
+// whichever driver began it.
+func (r *BaseRepo) postgresTx(ctx context.Context) (SQLTx, bool) {
+	active, ok := sqlTxFromContext(ctx, r.keys.postgres)
+	if !ok {
+		return nil, false
+	}
+	return active.tx, true
+}
+
+// sqlTxFromContext retrieves the database/sql transaction stored under key.
+func sqlTxFromContext(ctx context.Context, key contextKey) (*sqlTx, bool) {
+	active, ok := ctx.Value(key).(*sqlTx)
+	return active, ok
+}
+
 // GetTimescaleTx retrieves a TimescaleDB transaction from the context.
 func (r *BaseRepo) GetTimescaleTx(ctx context.Context) (pgx.Tx, bool) {
-	tx, ok := ctx.Value(timescaleTxKey).(pgx.Tx)
-	return tx, ok
+	active, ok := r.timescaleTxFromContext(ctx)
+	if !ok {
+		return nil, false
+	}
+	return active.tx, true
+}
+
+// timescaleTxFromContext retrieves the TimescaleDB transaction state
+// stored under this repository's key.
+func (r *BaseRepo) timescaleTxFromContext(ctx context.Context) (*timescaleTx, bool) {
+	active, ok := ctx.Value(r.keys.timescale).(*timescaleTx)
+	return active, ok
+}
+
+// IsInPostgresTx reports whether the context carries a PostgreSQL
+// transaction.
+func (r *BaseRepo) IsInPostgresTx(ctx context.Context) bool {
+	_, ok := r.postgresTx(ctx)
+	return ok
+}
+
+// IsInTimescaleTx reports whether the context carries a TimescaleDB
+// transaction.
+func (r *BaseRepo) IsInTimescaleTx(ctx context.Context) bool {
+	_, ok := r.GetTimescaleTx(ctx)
+	return ok
 }
 
 // -----------------------------
@@ -144,17 +982,16 @@ func (r *BaseRepo) GetTimescaleTx(ctx context.Context) (pgx.Tx, bool) {
 
 // PostgresQueryExecutor returns a PostgreSQL query executor.
 //
-// If a transaction exists in the context, it is returned.
-// Otherwise, the base *sql.DB instance is used.
-func (r *BaseRepo) PostgresQueryExecutor(ctx context.Context) interface {
-	ExecContext(context.Context, string, ...any) (sql.Result, error)
-	QueryContext(context.Context, string, ...any) (*sql.Rows, error)
-	QueryRowContext(context.Context, string, ...any) *sql.Row
-} {
-	if tx, ok := r.GetTxFromContext(ctx); ok {
-		return tx
+// If a transaction exists in the context, statements run in it.
+// Otherwise, the base *sql.DB instance is used, or one of the read
+// replicas if any are configured and the context was marked with
+// MarkReadOnly.
+func (r *BaseRepo) PostgresQueryExecutor(ctx context.Context) SQLExecutor {
+	if active, ok := sqlTxFromContext(ctx, r.keys.postgres); ok {
+		return newTxExecutor(active)
 	}
-	return r.postgresDB
+	r.checkContext(ctx, BackendPostgres, r.keys.postgres)
+	return r.postgresReader(ctx)
 }
 
 // TimescaleQueryExecutor returns a TimescaleDB query executor.
@@ -169,5 +1006,6 @@ func (r *BaseRepo) TimescaleQueryExecutor(ctx context.Context) interface {
 	if tx, ok := r.GetTimescaleTx(ctx); ok {
 		return tx
 	}
+	r.checkContext(ctx, BackendTimescale, r.keys.timescale)
 	return r.timescaleDB
 }
@@ -0,0 +1,109 @@
+package tx
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// -----------------------------
+// Manual Transactions
+// -----------------------------
+
+// BeginPostgresTx starts a PostgreSQL transaction managed by the caller,
+// for logic that cannot be expressed as a single function.
+//
+// It returns the transaction, a context carrying it for use with
+// repositories and nested helpers, and a finisher. Calling the finisher
+// with a nil error commits the transaction; any other error rolls it
+// back. The finisher returns what WithPostgresDBTx would: the error
+// passed to it joined with any rollback error, the commit error, or nil.
+// Calling it again returns the same result without side effects.
+//
+// The finisher must always be called, typically in a defer; until then
+// the transaction holds its connection and locks. If the context is done
+// first, the transaction is rolled back and the finisher reports
+// ctx.Err(). Everything else, including hooks, callbacks and reuse of a
+// transaction already in the context, behaves as with WithPostgresDBTx;
+// when a transaction is reused, the finisher only returns its argument.
+// WithDefaultPostgresRetry does not apply, since the caller's work
+// cannot be re-run by the repository. The returned *sql.Tx is nil for
+// transactions begun by a SQLDriver that are not a *sql.Tx; use the
+// context with PostgresQueryExecutor instead.
+func (r *BaseRepo) BeginPostgresTx(ctx context.Context) (*sql.Tx, context.Context, func(error) error, error) {
+	txCtx, finisher, err := beginManual(ctx, func(ctx context.Context, fn func(ctx context.Context) error) error {
+		return r.withSQLTx(ctx, r.postgres(), nil, txConfig{}, fn)
+	})
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	tx, _ := r.GetTxFromContext(txCtx)
+	return tx, txCtx, finisher, nil
+}
+
+// BeginTimescaleTx starts a TimescaleDB transaction managed by the
+// caller, for cases the single-function model makes awkward, such as
+// streaming rows while the transaction is open and processing them
+// incrementally.
+//
+// It returns the pgx.Tx, a context carrying it and a finisher, with the
+// same semantics as BeginPostgresTx; WithDefaultTimescaleRetry does not
+// apply either. The caller must always call the finisher, typically in a
+// defer; until then the transaction holds its connection and locks.
+func (r *BaseRepo) BeginTimescaleTx(ctx context.Context) (pgx.Tx, context.Context, func(error) error, error) {
+	txCtx, finisher, err := beginManual(ctx, func(ctx context.Context, fn func(ctx context.Context) error) error {
+		return r.withTimescaleTx(ctx, pgx.TxOptions{}, fn, nil)
+	})
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	tx, _ := r.GetTimescaleTx(txCtx)
+	return tx, txCtx, finisher, nil
+}
+
+// beginManual runs a transaction with withTx on a separate goroutine and
+// returns its context as soon as it has begun, along with the finisher
+// that ends it with the given error and reports the outcome.
+func beginManual(
+	ctx context.Context,
+	withTx func(ctx context.Context, fn func(ctx context.Context) error) error,
+) (context.Context, func(error) error, error) {
+
+	started := make(chan context.Context)
+	finish := make(chan error, 1)
+	done := make(chan error, 1)
+
+	go func() {
+		done <- withTx(ctx, func(txCtx context.Context) error {
+			started <- txCtx
+			select {
+			case err := <-finish:
+				return err
+			case <-txCtx.Done():
+				return txCtx.Err()
+			}
+		})
+	}()
+
+	var txCtx context.Context
+	select {
+	case txCtx = <-started:
+	case err := <-done:
+		return nil, nil, err
+	}
+
+	var once sync.Once
+	var result error
+	finisher := func(err error) error {
+		once.Do(func() {
+			finish <- err
+			result = <-done
+		})
+		return result
+	}
+	return txCtx, finisher, nil
+}
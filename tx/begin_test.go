@@ -0,0 +1,83 @@
+package tx_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/arunni/go-db-tx/tx"
+	"github.com/arunni/go-db-tx/tx/txtest"
+)
+
+// finishWithin calls finisher with err and fails the test if it does not
+// return within a second.
+func finishWithin(t *testing.T, finisher func(error) error, err error) error {
+	t.Helper()
+
+	result := make(chan error, 1)
+	go func() { result <- finisher(err) }()
+	select {
+	case err := <-result:
+		return err
+	case <-time.After(time.Second):
+		t.Fatal("finisher did not return")
+		return nil
+	}
+}
+
+func TestBeginPostgresTxCommit(t *testing.T) {
+	spy := txtest.NewSpy()
+	r := tx.NewBaseRepo(spy.DB(), nil)
+
+	sqlTx, ctx, finisher, err := r.BeginPostgresTx(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sqlTx == nil || !r.IsInPostgresTx(ctx) {
+		t.Fatal("BeginPostgresTx did not return the open transaction")
+	}
+	if _, err := r.PostgresQueryExecutor(ctx).ExecContext(ctx, "UPDATE a"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := finishWithin(t, finisher, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := finishWithin(t, finisher, errors.New("ignored")); err != nil {
+		t.Fatalf("second finisher call = %v, want the first result", err)
+	}
+	assertKinds(t, spy, txtest.CallBegin, txtest.CallExec, txtest.CallCommit)
+}
+
+func TestBeginPostgresTxRollback(t *testing.T) {
+	spy := txtest.NewSpy()
+	r := tx.NewBaseRepo(spy.DB(), nil)
+	boom := errors.New("boom")
+
+	_, _, finisher, err := r.BeginPostgresTx(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := finishWithin(t, finisher, boom); !errors.Is(err, boom) {
+		t.Fatalf("err = %v, want boom", err)
+	}
+	assertKinds(t, spy, txtest.CallBegin, txtest.CallRollback)
+}
+
+func TestBeginPostgresTxIgnoresDefaultRetry(t *testing.T) {
+	spy := txtest.NewSpy()
+	r := tx.NewBaseRepo(spy.DB(), nil,
+		tx.WithClock(&sleepClock{}),
+		tx.WithDefaultPostgresRetry(&tx.RetryConfig{MaxAttempts: 3}),
+	)
+
+	_, _, finisher, err := r.BeginPostgresTx(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := finishWithin(t, finisher, errSerialization); !errors.Is(err, errSerialization) {
+		t.Fatalf("err = %v, want the serialization failure", err)
+	}
+	assertKinds(t, spy, txtest.CallBegin, txtest.CallRollback)
+}
@@ -0,0 +1,92 @@
+package tx
+
+import "context"
+
+// RegisterAfterCommit queues fn to run after the transaction in the
+// context commits successfully.
+//
+// Callbacks never run if the transaction rolls back or panics. Callbacks
+// registered in nested calls, including savepoints, attach to the root
+// transaction and run once it commits, in registration order. Work rolled
+// back to a savepoint does not unregister its callbacks.
+//
+// ErrNoTransaction is returned if the context carries no transaction.
+func RegisterAfterCommit(ctx context.Context, fn func()) error {
+	scope, ok := scopeFromContext(ctx)
+	if !ok {
+		return ErrNoTransaction
+	}
+
+	scope.mu.Lock()
+	scope.afterCommit = append(scope.afterCommit, fn)
+	scope.mu.Unlock()
+	return nil
+}
+
+// RegisterAfterRollback queues fn to run after the transaction in the
+// context is rolled back.
+//
+// Callbacks run once the rollback has completed, whether it was caused by
+// an error, a panic or a failed commit; on the panic path the panic is
+// re-raised after they have run. Callbacks registered in nested calls,
+// including savepoints, attach to the root transaction: rolling back to a
+// savepoint does not trigger them. They never run if the transaction
+// commits.
+//
+// ErrNoTransaction is returned if the context carries no transaction.
+func RegisterAfterRollback(ctx context.Context, fn func()) error {
+	scope, ok := scopeFromContext(ctx)
+	if !ok {
+		return ErrNoTransaction
+	}
+
+	scope.mu.Lock()
+	scope.afterRollback = append(scope.afterRollback, fn)
+	scope.mu.Unlock()
+	return nil
+}
+
+// DeferUntilCommit queues fn to run inside the transaction in the
+// context, right before it commits.
+//
+// Unlike after-commit callbacks, deferred functions run within the
+// transaction and can still write to it, for example to recompute an
+// aggregate once after a batch of changes. They run in registration
+// order, with the context of the root transaction, once the function
+// passed to the outermost helper has succeeded. If one returns an error,
+// the remaining ones are skipped and the transaction is rolled back with
+// that error. Functions deferred in nested calls, including savepoints,
+// attach to the root transaction; rolling back to a savepoint does not
+// unregister them.
+//
+// ErrNoTransaction is returned if the context carries no transaction.
+func DeferUntilCommit(ctx context.Context, fn func(ctx context.Context) error) error {
+	scope, ok := scopeFromContext(ctx)
+	if !ok {
+		return ErrNoTransaction
+	}
+
+	scope.mu.Lock()
+	scope.beforeCommit = append(scope.beforeCommit, fn)
+	scope.mu.Unlock()
+	return nil
+}
+
+// SetRollbackOnly marks the transaction in the context rollback-only: it
+// is rolled back when the outermost helper finishes, whatever fn
+// returns, and the helper returns nil unless fn or the rollback failed.
+// Marking a transaction from a nested call or a savepoint affects the
+// whole root transaction; after-rollback callbacks run as usual.
+//
+// ErrNoTransaction is returned if the context carries no transaction.
+func SetRollbackOnly(ctx context.Context) error {
+	scope, ok := scopeFromContext(ctx)
+	if !ok {
+		return ErrNoTransaction
+	}
+
+	scope.mu.Lock()
+	scope.rollbackOnly = true
+	scope.mu.Unlock()
+	return nil
+}
@@ -0,0 +1,178 @@
+package tx_test
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/arunni/go-db-tx/tx"
+	"github.com/arunni/go-db-tx/tx/txtest"
+)
+
+func TestCallbackOrdering(t *testing.T) {
+	spy := txtest.NewSpy()
+	r := tx.NewBaseRepo(spy.DB(), nil)
+
+	var events []string
+	record := func(event string) func() {
+		return func() { events = append(events, event) }
+	}
+
+	err := r.WithPostgresDBTx(context.Background(), func(ctx context.Context) error {
+		_ = tx.RegisterAfterCommit(ctx, record("after commit 1"))
+		_ = tx.RegisterAfterRollback(ctx, record("after rollback"))
+		_ = tx.DeferUntilCommit(ctx, func(ctx context.Context) error {
+			events = append(events, "deferred 1")
+			_, err := r.PostgresQueryExecutor(ctx).ExecContext(ctx, "UPDATE totals")
+			return err
+		})
+		_ = tx.DeferUntilCommit(ctx, func(ctx context.Context) error {
+			events = append(events, "deferred 2")
+			return tx.DeferUntilCommit(ctx, func(ctx context.Context) error {
+				events = append(events, "deferred by deferred")
+				return nil
+			})
+		})
+
+		return r.WithPostgresDBSavepoint(ctx, func(ctx context.Context) error {
+			return tx.RegisterAfterCommit(ctx, record("after commit 2"))
+		})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"deferred 1", "deferred 2", "deferred by deferred", "after commit 1", "after commit 2"}
+	if !slices.Equal(events, want) {
+		t.Fatalf("events = %q, want %q", events, want)
+	}
+	calls := spy.Calls()
+	if last := calls[len(calls)-1]; last.Kind != txtest.CallCommit {
+		t.Fatalf("last call = %v, want commit", last.Kind)
+	}
+	if update := calls[len(calls)-2]; update.Query != "UPDATE totals" || !update.InTx {
+		t.Fatalf("call before commit = %+v, want the deferred UPDATE in the transaction", update)
+	}
+}
+
+func TestDeferUntilCommitError(t *testing.T) {
+	spy := txtest.NewSpy()
+	r := tx.NewBaseRepo(spy.DB(), nil)
+	boom := errors.New("boom")
+
+	var rolledBack, ran bool
+	err := r.WithPostgresDBTx(context.Background(), func(ctx context.Context) error {
+		_ = tx.RegisterAfterRollback(ctx, func() { rolledBack = true })
+		_ = tx.DeferUntilCommit(ctx, func(ctx context.Context) error { return boom })
+		return tx.DeferUntilCommit(ctx, func(ctx context.Context) error {
+			ran = true
+			return nil
+		})
+	})
+	if !errors.Is(err, boom) {
+		t.Fatalf("err = %v, want boom", err)
+	}
+	if !rolledBack || ran {
+		t.Fatalf("rolledBack = %v, ran = %v, want the rollback without the remaining deferred function", rolledBack, ran)
+	}
+	assertKinds(t, spy, txtest.CallBegin, txtest.CallRollback)
+}
+
+func TestSetRollbackOnly(t *testing.T) {
+	spy := txtest.NewSpy()
+	r := tx.NewBaseRepo(spy.DB(), nil)
+
+	var deferred, committed, rolledBack bool
+	err := r.WithPostgresDBTx(context.Background(), func(ctx context.Context) error {
+		_ = tx.DeferUntilCommit(ctx, func(ctx context.Context) error {
+			deferred = true
+			return nil
+		})
+		_ = tx.RegisterAfterCommit(ctx, func() { committed = true })
+		_ = tx.RegisterAfterRollback(ctx, func() { rolledBack = true })
+
+		return r.WithPostgresDBTx(ctx, func(ctx context.Context) error {
+			return tx.SetRollbackOnly(ctx)
+		})
+	})
+	if err != nil {
+		t.Fatalf("err = %v, want nil for a rollback-only transaction", err)
+	}
+	if !deferred || committed || !rolledBack {
+		t.Fatalf("deferred = %v, committed = %v, rolledBack = %v", deferred, committed, rolledBack)
+	}
+	assertKinds(t, spy, txtest.CallBegin, txtest.CallRollback)
+}
+
+func TestErrRollbackOnly(t *testing.T) {
+	spy := txtest.NewSpy()
+	r := tx.NewBaseRepo(spy.DB(), nil)
+
+	err := r.WithPostgresDBTx(context.Background(), func(ctx context.Context) error {
+		return tx.ErrRollbackOnly
+	})
+	if err != nil {
+		t.Fatalf("err = %v, want nil", err)
+	}
+	assertKinds(t, spy, txtest.CallBegin, txtest.CallRollback)
+}
+
+func TestCallbacksWithoutTransaction(t *testing.T) {
+	ctx := context.Background()
+	if err := tx.RegisterAfterCommit(ctx, func() {}); !errors.Is(err, tx.ErrNoTransaction) {
+		t.Errorf("RegisterAfterCommit err = %v, want ErrNoTransaction", err)
+	}
+	if err := tx.DeferUntilCommit(ctx, func(context.Context) error { return nil }); !errors.Is(err, tx.ErrNoTransaction) {
+		t.Errorf("DeferUntilCommit err = %v, want ErrNoTransaction", err)
+	}
+	if err := tx.SetRollbackOnly(ctx); !errors.Is(err, tx.ErrNoTransaction) {
+		t.Errorf("SetRollbackOnly err = %v, want ErrNoTransaction", err)
+	}
+}
+
+func TestPanicAfterCommit(t *testing.T) {
+	tests := []struct {
+		name     string
+		register func(ctx context.Context)
+		hooks    []tx.TxHook
+	}{
+		{
+			name: "after-commit callback",
+			register: func(ctx context.Context) {
+				_ = tx.RegisterAfterCommit(ctx, func() { panic("kaboom") })
+			},
+		},
+		{
+			name:     "commit hook",
+			register: func(context.Context) {},
+			hooks:    []tx.TxHook{commitPanicHook{}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spy := txtest.NewSpy()
+			rec := &recordingHook{}
+			hooks := append([]tx.TxHook{rec}, tt.hooks...)
+			r := tx.NewBaseRepo(spy.DB(), nil, tx.RecoverPanics(true), tx.WithHooks(hooks...))
+
+			func() {
+				defer func() {
+					if p := recover(); p != "kaboom" {
+						t.Fatalf("recovered %v, want the panic to propagate", p)
+					}
+				}()
+				_ = r.WithPostgresDBTx(context.Background(), func(ctx context.Context) error {
+					tt.register(ctx)
+					return nil
+				})
+			}()
+
+			assertKinds(t, spy, txtest.CallBegin, txtest.CallCommit)
+			if want := []string{"begin", "commit"}; !slices.Equal(rec.events(), want) {
+				t.Fatalf("hook events = %q, want %q", rec.events(), want)
+			}
+		})
+	}
+}
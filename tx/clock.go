@@ -0,0 +1,37 @@
+package tx
+
+import "time"
+
+// Clock is the source of time used by the repository for transaction
+// start times and durations, slow transaction warnings, retry backoff
+// and replica cooldowns. Tests can provide a fake clock to drive these
+// deterministically.
+//
+// Implementations must be safe for concurrent use.
+type Clock interface {
+	// Now returns the current time.
+	Now() time.Time
+
+	// After returns a channel that receives the current time once d has
+	// elapsed.
+	After(d time.Duration) <-chan time.Time
+}
+
+// realClock is the Clock backed by package time.
+type realClock struct{}
+
+func (realClock) Now() time.Time                         { return time.Now() }
+func (realClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+
+// WithClock sets the Clock used by the repository. When no clock is
+// provided, the system clock is used.
+//
+// Deadlines and timeouts enforced through contexts, such as
+// BeginTimeout, and the timestamps of trace spans are not affected.
+func WithClock(clock Clock) Option {
+	return func(r *BaseRepo) {
+		if clock != nil {
+			r.clock = clock
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package tx
+
+import (
+	"context"
+	"errors"
+)
+
+// -----------------------------
+// CockroachDB Transaction
+// -----------------------------
+
+// cockroachSavepoint is the savepoint name CockroachDB reserves for its
+// client-side retry protocol.
+const cockroachSavepoint = "cockroach_restart"
+
+// defaultCockroachAttempts bounds the attempts of WithCockroachTx unless
+// changed with CockroachMaxAttempts.
+const defaultCockroachAttempts = 10
+
+// CockroachMaxAttempts bounds the number of times WithCockroachTx runs
+// fn within one transaction, including the first. It defaults to 10.
+func CockroachMaxAttempts(n int) Option {
+	return func(r *BaseRepo) {
+		if n > 0 {
+			r.cockroachAttempts = n
+		}
+	}
+}
+
+// WithCockroachTx executes the given function within a transaction on
+// the PostgreSQL database, using the client-side retry protocol that
+// CockroachDB recommends for its serializable transactions.
+//
+// The transaction sets SAVEPOINT cockroach_restart as its first
+// statement and runs fn. When fn, or the RELEASE of the savepoint, fails
+// with a retry error (SQLSTATE 40001), the transaction is rolled back to
+// the savepoint and fn runs again, on the same connection and without
+// starting over, up to the limit set by CockroachMaxAttempts, after which
+// a *RetryExhaustedError is returned and reported like any exhausted
+// retry, to the log and RetryHook hooks. Once the savepoint is released,
+// the transaction commits. Any other error rolls the transaction back.
+// WithDefaultPostgresRetry does not apply to the transaction.
+//
+// fn may run more than once and must not have side effects outside the
+// transaction. Callbacks registered by an attempt that is retried are
+// settled as rolled back; those of the successful attempt run with the
+// transaction. If a transaction already exists in the context, fn is run
+// once within it: only the caller that started it can restart it.
+func (r *BaseRepo) WithCockroachTx(
+	ctx context.Context,
+	fn func(ctx context.Context) error,
+) error {
+
+	if r.IsInPostgresTx(ctx) {
+		return fn(withOutermost(ctx, false))
+	}
+
+	// The default retry is bypassed: restarts happen within the
+	// transaction, and retrying it as a whole would multiply the attempts.
+	var exhausted *RetryExhaustedError
+	err := r.withSQLTx(ctx, r.postgres(), nil, txConfig{}, func(ctx context.Context) error {
+		tx, _ := r.postgresTx(ctx)
+		scope, _ := scopeFromContext(ctx)
+		exec := func(query string) error {
+			_, err := tx.ExecContext(ctx, query)
+			return err
+		}
+
+		if err := exec("SAVEPOINT " + cockroachSavepoint); err != nil {
+			return err
+		}
+
+		for attempt := 1; ; attempt++ {
+			attemptCtx, attemptScope := newScopeContext(withAttempt(ctx, attempt), r.clock)
+			err := r.cockroachAttempt(attemptCtx, attemptScope, fn)
+			if err == nil {
+				err = exec("RELEASE SAVEPOINT " + cockroachSavepoint)
+			}
+			if err == nil {
+				scope.adopt(attemptScope)
+				return nil
+			}
+
+			if !IsSerializationFailure(err) {
+				scope.adopt(attemptScope)
+				return err
+			}
+			attemptScope.rolledBack()
+			if attempt >= r.cockroachAttempts {
+				exhausted = &RetryExhaustedError{Attempts: attempt, Err: err}
+				return exhausted
+			}
+			if rbErr := exec("ROLLBACK TO SAVEPOINT " + cockroachSavepoint); rbErr != nil {
+				return errors.Join(err, rbErr)
+			}
+			r.metrics.IncRetried(BackendPostgres)
+		}
+	})
+	if exhausted != nil {
+		r.reportExhausted(ctx, BackendPostgres, exhausted.Attempts, exhausted.Err)
+	}
+	return err
+}
+
+// cockroachAttempt runs one attempt of fn within scope, including the
+// functions it deferred until commit, and reports ErrRollbackOnly if the
+// attempt asked for a rollback.
+func (r *BaseRepo) cockroachAttempt(
+	ctx context.Context,
+	scope *txScope,
+	fn func(ctx context.Context) error,
+) error {
+
+	if err := fn(ctx); err != nil {
+		return err
+	}
+	if err := scope.runBeforeCommit(ctx); err != nil {
+		return err
+	}
+	if scope.isRollbackOnly() {
+		return ErrRollbackOnly
+	}
+	return nil
+}
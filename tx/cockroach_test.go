@@ -0,0 +1,132 @@
+package tx_test
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/arunni/go-db-tx/tx"
+	"github.com/arunni/go-db-tx/tx/txtest"
+)
+
+func TestCockroachRestart(t *testing.T) {
+	spy := txtest.NewSpy()
+	r := tx.NewBaseRepo(spy.DB(), nil, tx.CockroachMaxAttempts(3))
+
+	attempts := 0
+	var committed, rolledBack int
+	err := r.WithCockroachTx(context.Background(), func(ctx context.Context) error {
+		attempts++
+		_ = tx.RegisterAfterCommit(ctx, func() { committed++ })
+		_ = tx.RegisterAfterRollback(ctx, func() { rolledBack++ })
+		if attempts < 3 {
+			return errSerialization
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if attempts != 3 || committed != 1 || rolledBack != 2 {
+		t.Fatalf("attempts = %d, committed = %d, rolledBack = %d, want 3, 1, 2", attempts, committed, rolledBack)
+	}
+
+	assertQueries(t, spy,
+		"SAVEPOINT cockroach_restart",
+		"ROLLBACK TO SAVEPOINT cockroach_restart",
+		"ROLLBACK TO SAVEPOINT cockroach_restart",
+		"RELEASE SAVEPOINT cockroach_restart",
+	)
+	calls := spy.Calls()
+	if first, last := calls[0].Kind, calls[len(calls)-1].Kind; first != txtest.CallBegin || last != txtest.CallCommit {
+		t.Fatalf("calls run from %v to %v, want a single transaction that commits", first, last)
+	}
+}
+
+func TestCockroachRestartExhausted(t *testing.T) {
+	spy := txtest.NewSpy()
+	r := tx.NewBaseRepo(spy.DB(), nil, tx.CockroachMaxAttempts(2))
+
+	attempts := 0
+	err := r.WithCockroachTx(context.Background(), func(ctx context.Context) error {
+		attempts++
+		return errSerialization
+	})
+
+	var exhausted *tx.RetryExhaustedError
+	if !errors.As(err, &exhausted) || exhausted.Attempts != 2 || attempts != 2 {
+		t.Fatalf("err = %v after %d attempts, want RetryExhaustedError after 2", err, attempts)
+	}
+	calls := spy.Calls()
+	if last := calls[len(calls)-1].Kind; last != txtest.CallRollback {
+		t.Fatalf("last call = %v, want rollback", last)
+	}
+}
+
+// exhaustedHook is a RetryHook that records the exhaustions it is told of.
+type exhaustedHook struct {
+	recordingHook
+	attempts []int
+}
+
+func (h *exhaustedHook) OnRetryExhausted(_ context.Context, _ tx.Backend, attempts int, _ error) {
+	h.attempts = append(h.attempts, attempts)
+}
+
+func TestCockroachRestartExhaustedNotifiesHooks(t *testing.T) {
+	spy := txtest.NewSpy()
+	hook := &exhaustedHook{}
+	r := tx.NewBaseRepo(spy.DB(), nil, tx.CockroachMaxAttempts(2), tx.WithHooks(hook))
+
+	_ = r.WithCockroachTx(context.Background(), func(ctx context.Context) error {
+		return errSerialization
+	})
+	if !slices.Equal(hook.attempts, []int{2}) {
+		t.Fatalf("OnRetryExhausted attempts = %v, want [2]", hook.attempts)
+	}
+}
+
+func TestCockroachIgnoresDefaultRetry(t *testing.T) {
+	spy := txtest.NewSpy()
+	r := tx.NewBaseRepo(spy.DB(), nil,
+		tx.CockroachMaxAttempts(2),
+		tx.WithDefaultPostgresRetry(&tx.RetryConfig{MaxAttempts: 3}),
+	)
+
+	attempts := 0
+	err := r.WithCockroachTx(context.Background(), func(ctx context.Context) error {
+		attempts++
+		return errSerialization
+	})
+
+	var exhausted *tx.RetryExhaustedError
+	if !errors.As(err, &exhausted) || attempts != 2 {
+		t.Fatalf("err = %v after %d attempts, want RetryExhaustedError after 2", err, attempts)
+	}
+	begins := 0
+	for _, c := range spy.Calls() {
+		if c.Kind == txtest.CallBegin {
+			begins++
+		}
+	}
+	if begins != 1 {
+		t.Fatalf("began %d transactions, want 1", begins)
+	}
+}
+
+func TestCockroachOtherError(t *testing.T) {
+	spy := txtest.NewSpy()
+	r := tx.NewBaseRepo(spy.DB(), nil)
+	boom := errors.New("boom")
+
+	attempts := 0
+	err := r.WithCockroachTx(context.Background(), func(ctx context.Context) error {
+		attempts++
+		return boom
+	})
+	if !errors.Is(err, boom) || attempts != 1 {
+		t.Fatalf("err = %v after %d attempts, want boom after 1", err, attempts)
+	}
+	assertQueries(t, spy, "SAVEPOINT cockroach_restart")
+}
@@ -0,0 +1,65 @@
+package tx
+
+import "context"
+
+// CompositeTxRepository is a TxRepository that runs fn within the
+// transactions of several repositories at once, such as BaseRepos for
+// independent clusters, so that usecases stay unaware of the setup.
+//
+// Transactions are nested in order: the first repository's transaction
+// is the outermost. If fn returns an error or panics, every transaction
+// is rolled back. Otherwise they commit innermost first, that is in the
+// reverse order of the repositories.
+//
+// This is best-effort coordination across independent databases, not a
+// distributed transaction: if a commit fails after an inner one has
+// succeeded, or the process crashes between commits, the inner changes
+// stay committed and the databases can be left inconsistent. Give each
+// BaseRepo its own namespace with WithNamespace so that their
+// transactions do not share context keys.
+type CompositeTxRepository struct {
+	repos []TxRepository
+}
+
+// Compile-time assertion to ensure CompositeTxRepository implements TxRepository.
+var _ TxRepository = (*CompositeTxRepository)(nil)
+
+// NewCompositeTxRepository creates a CompositeTxRepository over repos.
+func NewCompositeTxRepository(repos ...TxRepository) *CompositeTxRepository {
+	return &CompositeTxRepository{repos: repos}
+}
+
+// WithPostgresDBTx executes fn within the PostgreSQL transaction of
+// every repository.
+func (c *CompositeTxRepository) WithPostgresDBTx(
+	ctx context.Context,
+	fn func(ctx context.Context) error,
+) error {
+	return c.nest(ctx, 0, TxRepository.WithPostgresDBTx, fn)
+}
+
+// WithTimescaleDBTx executes fn within the TimescaleDB transaction of
+// every repository.
+func (c *CompositeTxRepository) WithTimescaleDBTx(
+	ctx context.Context,
+	fn func(ctx context.Context) error,
+) error {
+	return c.nest(ctx, 0, TxRepository.WithTimescaleDBTx, fn)
+}
+
+// nest runs fn within the transactions started with with on the
+// repositories from index i onwards.
+func (c *CompositeTxRepository) nest(
+	ctx context.Context,
+	i int,
+	with func(TxRepository, context.Context, func(ctx context.Context) error) error,
+	fn func(ctx context.Context) error,
+) error {
+
+	if i == len(c.repos) {
+		return fn(ctx)
+	}
+	return with(c.repos[i], ctx, func(ctx context.Context) error {
+		return c.nest(ctx, i+1, with, fn)
+	})
+}
@@ -0,0 +1,60 @@
+package tx
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// -----------------------------
+// PostgreSQL Drivers
+// -----------------------------
+
+// SQLTx is a transaction begun by a SQLDriver. *sql.Tx implements it.
+type SQLTx interface {
+	SQLExecutor
+	Commit() error
+	Rollback() error
+}
+
+// SQLDriver begins the PostgreSQL transactions of a repository created
+// with NewBaseRepoWithDrivers and runs the statements made outside them.
+//
+// It lets database/sql-compatible databases with their own transaction
+// semantics, such as CockroachDB or YugabyteDB, be supported by a wrapper
+// that customizes begin, commit or rollback, for example to implement a
+// database-specific retry protocol. Implementations must be safe for
+// concurrent use.
+//
+// If the driver also has a DB() *sql.DB method, the returned handle is
+// used by PostgresDB, Stats, HealthCheck and Close.
+type SQLDriver interface {
+	SQLExecutor
+	BeginTx(ctx context.Context, opts *sql.TxOptions) (SQLTx, error)
+}
+
+// NewBaseRepoWithDrivers creates a new BaseRepo whose PostgreSQL
+// transactions are begun by postgres instead of a *sql.DB.
+//
+// It behaves like NewBaseRepo, with these exceptions for transactions
+// whose SQLTx is not a *sql.Tx: GetTxFromContext and BeginPostgresTx do
+// not expose them, and CacheStatements does not apply. BeginTimeout is
+// not applied to driver-begun transactions either, since the driver
+// itself decides how a connection is acquired.
+func NewBaseRepoWithDrivers(postgres SQLDriver, timescaleDB *pgxpool.Pool, opts ...Option) *BaseRepo {
+	var db *sql.DB
+	if d, ok := postgres.(interface{ DB() *sql.DB }); ok {
+		db = d.DB()
+	}
+
+	r := NewBaseRepo(db, timescaleDB, opts...)
+	r.postgresDriver = postgres
+	return r
+}
+
+// beginDriver begins a transaction with the backend's driver.
+func (b sqlBackend) beginDriver(ctx context.Context, opts *sql.TxOptions) (SQLTx, func(), error) {
+	tx, err := b.driver.BeginTx(ctx, opts)
+	return tx, func() {}, err
+}
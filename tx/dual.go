@@ -0,0 +1,95 @@
+package tx
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// -----------------------------
+// Cross-Database Transaction
+// -----------------------------
+
+// WithDualTx executes the given function within both a PostgreSQL and
+// a TimescaleDB transaction; fn sees both in its context.
+//
+// If fn returns an error or panics, both transactions are rolled back.
+// Otherwise PostgreSQL is committed first and TimescaleDB second; if the
+// PostgreSQL commit fails, the TimescaleDB transaction is rolled back.
+//
+// This is best-effort coordination, not a true distributed (XA)
+// transaction: if the TimescaleDB commit fails, or the process crashes
+// between the two commits, the PostgreSQL changes stay committed and the
+// databases can be left inconsistent.
+//
+// A rollback requested with ErrRollbackOnly or SetRollbackOnly rolls
+// back both transactions. Callbacks registered with RegisterAfterCommit
+// or RegisterAfterRollback inside fn run once both transactions are
+// finished. Transactions already present in the context are reused.
+//
+// With WithDefaultPostgresRetry, or else WithDefaultTimescaleRetry, both
+// transactions are retried together as one unit, so fn must not have
+// side effects outside them.
+func (r *BaseRepo) WithDualTx(
+	ctx context.Context,
+	fn func(ctx context.Context) error,
+) error {
+
+	backend, cfg := BackendPostgres, r.postgresRetry
+	if cfg == nil {
+		backend, cfg = BackendTimescale, r.timescaleRetry
+	}
+	if cfg != nil && outsideTx(ctx) {
+		return r.retry(ctx, backend, *cfg, func(ctx context.Context) error {
+			return r.withDualTx(ctx, fn)
+		})
+	}
+	return r.withDualTx(ctx, fn)
+}
+
+// withDualTx implements WithDualTx, without retries.
+func (r *BaseRepo) withDualTx(
+	ctx context.Context,
+	fn func(ctx context.Context) error,
+) error {
+
+	// The inner transaction commits first, so PostgreSQL is nested
+	// inside TimescaleDB.
+	return r.withTimescaleTx(ctx, pgx.TxOptions{}, func(ctx context.Context) error {
+		// fn gets a scope of its own, settled with the TimescaleDB
+		// transaction so that its callbacks run after both commits.
+		tsScope, _ := scopeFromContext(ctx)
+		var scope *txScope
+		defer func() {
+			if scope != nil {
+				tsScope.adopt(scope)
+			}
+		}()
+
+		var rollbackOnly bool
+		err := r.WithPostgresDBTx(withIntendedNesting(ctx, BackendPostgres), func(ctx context.Context) error {
+			var fnCtx context.Context
+			fnCtx, scope = newScopeContext(ctx, r.clock)
+			err := fn(fnCtx)
+			if err == nil {
+				err = scope.runBeforeCommit(fnCtx)
+			}
+			if err == nil && scope.isRollbackOnly() {
+				err = ErrRollbackOnly
+			}
+			rollbackOnly = errors.Is(err, ErrRollbackOnly)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+
+		// PostgreSQL was rolled back deliberately: do not commit
+		// TimescaleDB either.
+		if rollbackOnly {
+			return ErrRollbackOnly
+		}
+		return nil
+	}, nil)
+}
@@ -0,0 +1,27 @@
+package tx_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/arunni/go-db-tx/tx"
+	"github.com/arunni/go-db-tx/tx/txtest"
+)
+
+func TestDualTxNeedsTimescale(t *testing.T) {
+	spy := txtest.NewSpy()
+	r := tx.NewBaseRepo(spy.DB(), nil, tx.WithDefaultPostgresRetry(&tx.RetryConfig{MaxAttempts: 3}))
+
+	called := false
+	err := r.WithDualTx(context.Background(), func(context.Context) error {
+		called = true
+		return nil
+	})
+	if !errors.Is(err, tx.ErrTimescaleNotConfigured) || called {
+		t.Fatalf("err = %v, called = %v, want ErrTimescaleNotConfigured without calling fn", err, called)
+	}
+	if calls := spy.Calls(); len(calls) != 0 {
+		t.Fatalf("calls = %v, want none", calls)
+	}
+}
@@ -0,0 +1,31 @@
+package tx
+
+import (
+	"context"
+	"time"
+)
+
+// TxStartedAt returns when the transaction in the context began.
+//
+// Nested calls, savepoints and transactions on other backends started
+// within it report the start of the outermost transaction. It reports
+// false if the context carries no transaction.
+func TxStartedAt(ctx context.Context) (time.Time, bool) {
+	scope, ok := scopeFromContext(ctx)
+	if !ok {
+		return time.Time{}, false
+	}
+	return scope.startedAt, true
+}
+
+// TxElapsed returns how long the transaction in the context has been
+// open, as reported by TxStartedAt, for example to skip optional work in
+// transactions that are already long. It returns zero if the context
+// carries no transaction.
+func TxElapsed(ctx context.Context) time.Duration {
+	scope, ok := scopeFromContext(ctx)
+	if !ok {
+		return 0
+	}
+	return scope.clock.Now().Sub(scope.startedAt)
+}
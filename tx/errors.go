@@ -0,0 +1,127 @@
+package tx
+
+import (
+	"errors"
+	"fmt"
+	"runtime/debug"
+)
+
+var (
+	// ErrNoTransaction is returned by helpers that require an active
+	// transaction in the context when none is found.
+	ErrNoTransaction = errors.New("tx: no transaction in context")
+
+	// ErrNestedTxOptionsConflict is returned when a call requests
+	// transaction options that the transaction already active in the
+	// context cannot satisfy, such as a different isolation level.
+	ErrNestedTxOptionsConflict = errors.New("tx: requested options conflict with active transaction")
+
+	// ErrRollbackOnly can be returned by a transaction function to roll
+	// the transaction back deliberately, such as in a dry run, without
+	// reporting an error: the helper that started the transaction rolls
+	// it back and returns nil. See also SetRollbackOnly.
+	ErrRollbackOnly = errors.New("tx: transaction is rollback-only")
+
+	// ErrTxClosedPrematurely is returned when a helper cannot commit its
+	// transaction because it was already committed or rolled back, for
+	// example through the *sql.Tx returned by GetTxFromContext. Whether
+	// the work was kept depends on how the transaction was closed.
+	// Transactions must only be finished by the helper that started them.
+	ErrTxClosedPrematurely = errors.New("tx: transaction closed before the helper that started it finished")
+
+	// ErrTxAborted is returned when a transaction cannot be committed
+	// because an earlier statement in it failed, leaving it aborted
+	// (SQLSTATE 25P02): PostgreSQL then rolls the whole transaction back
+	// on commit. It usually means an error from a statement inside fn
+	// was ignored; use a savepoint for statements allowed to fail.
+	ErrTxAborted = errors.New("tx: transaction aborted by an earlier failed statement, rolled back on commit")
+
+	// ErrBeginTimeout is returned when a transaction could not be
+	// started within the timeout configured with BeginTimeout.
+	ErrBeginTimeout = errors.New("tx: timed out beginning transaction")
+
+	// ErrTxLifetimeExceeded is returned when a transaction was aborted
+	// because it stayed open longer than allowed by MaxTxLifetime.
+	ErrTxLifetimeExceeded = errors.New("tx: transaction exceeded its maximum lifetime")
+
+	// ErrPostgresNotConfigured is returned when a PostgreSQL transaction
+	// is requested from a BaseRepo created without a *sql.DB.
+	ErrPostgresNotConfigured = errors.New("tx: postgres database not configured")
+
+	// ErrTimescaleNotConfigured is returned when a TimescaleDB transaction
+	// is requested from a BaseRepo created without a *pgxpool.Pool.
+	ErrTimescaleNotConfigured = errors.New("tx: timescale pool not configured")
+
+	// ErrMySQLNotConfigured is returned when a MySQL transaction is
+	// requested without WithMySQLDB.
+	ErrMySQLNotConfigured = errors.New("tx: mysql database not configured")
+
+	// ErrSQLiteNotConfigured is returned when a SQLite transaction is
+	// requested without WithSQLiteDB.
+	ErrSQLiteNotConfigured = errors.New("tx: sqlite database not configured")
+)
+
+// errNotConfigured returns the sentinel reported when backend has no
+// database handle.
+func errNotConfigured(backend Backend) error {
+	switch backend {
+	case BackendTimescale:
+		return ErrTimescaleNotConfigured
+	case BackendMySQL:
+		return ErrMySQLNotConfigured
+	case BackendSQLite:
+		return ErrSQLiteNotConfigured
+	}
+	return ErrPostgresNotConfigured
+}
+
+// PanicError describes a panic recovered inside a transaction.
+//
+// It is recorded as the rollback cause of the transaction, so logs and
+// traces show the panic value together with the stack at the point the
+// panic happened.
+type PanicError struct {
+	// Value is the value passed to panic.
+	Value any
+
+	// Stack is the goroutine stack captured when the panic was
+	// recovered, including the frames that raised it.
+	Stack []byte
+}
+
+// newPanicError captures the current stack for p. It must be called from
+// the deferred function that recovered p.
+func newPanicError(p any) *PanicError {
+	return &PanicError{Value: p, Stack: debug.Stack()}
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("panic in transaction: %v", e.Value)
+}
+
+// Unwrap returns the panic value if it is an error.
+func (e *PanicError) Unwrap() error {
+	err, _ := e.Value.(error)
+	return err
+}
+
+// RetryExhaustedError is returned by a retried transaction when every
+// attempt failed with a retryable error, as opposed to a failure that
+// was not retried, so that transactions contending on a hotspot can be
+// told apart.
+type RetryExhaustedError struct {
+	// Attempts is the number of attempts made.
+	Attempts int
+
+	// Err is the error of the last attempt.
+	Err error
+}
+
+func (e *RetryExhaustedError) Error() string {
+	return fmt.Sprintf("tx: giving up after %d attempts: %v", e.Attempts, e.Err)
+}
+
+// Unwrap returns the error of the last attempt.
+func (e *RetryExhaustedError) Unwrap() error {
+	return e.Err
+}
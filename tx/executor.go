@@ -0,0 +1,158 @@
+package tx
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+	"sync/atomic"
+)
+
+// txExecutor is the SQLExecutor handed out for an active database/sql
+// transaction. It embeds the transaction and accounts for the statements
+// run through it in the transaction's scope.
+type txExecutor struct {
+	SQLTx
+	scope  *txScope
+	stmts  *stmtCache
+	failed *failedStatement
+}
+
+// newTxExecutor returns the executor for the active transaction.
+func newTxExecutor(active *sqlTx) *txExecutor {
+	return &txExecutor{SQLTx: active.tx, scope: active.scope, stmts: active.stmts, failed: active.failed}
+}
+
+func (e *txExecutor) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	var res sql.Result
+	var err error
+	if stmt, ok := e.prepared(ctx, query); ok {
+		res, err = stmt.ExecContext(ctx, args...)
+	} else {
+		res, err = e.SQLTx.ExecContext(ctx, query, args...)
+	}
+
+	e.failed.record(query, err)
+	if err == nil {
+		if n, nErr := res.RowsAffected(); nErr == nil {
+			e.scope.rowsAffected.Add(n)
+		}
+	}
+	return res, err
+}
+
+func (e *txExecutor) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	var rows *sql.Rows
+	var err error
+	if stmt, ok := e.prepared(ctx, query); ok {
+		rows, err = stmt.QueryContext(ctx, args...)
+	} else {
+		rows, err = e.SQLTx.QueryContext(ctx, query, args...)
+	}
+	e.failed.record(query, err)
+	return rows, err
+}
+
+func (e *txExecutor) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	var row *sql.Row
+	if stmt, ok := e.prepared(ctx, query); ok {
+		row = stmt.QueryRowContext(ctx, args...)
+	} else {
+		row = e.SQLTx.QueryRowContext(ctx, query, args...)
+	}
+	e.failed.record(query, row.Err())
+	return row
+}
+
+// prepared returns the cached prepared statement for query, preparing
+// it on first use. It reports false when statement caching is disabled,
+// the transaction is not a *sql.Tx or preparing failed, in which case
+// the query is run unprepared so that its error surfaces as usual.
+func (e *txExecutor) prepared(ctx context.Context, query string) (*sql.Stmt, bool) {
+	tx, ok := e.SQLTx.(*sql.Tx)
+	if e.stmts == nil || !ok {
+		return nil, false
+	}
+	return e.stmts.get(ctx, tx, query)
+}
+
+// -----------------------------
+// Failed Statement
+// -----------------------------
+
+// failedStatement remembers the SQL text, without arguments, of the last
+// statement that failed within a transaction, see LogFailedStatements.
+// A nil *failedStatement records nothing.
+type failedStatement struct {
+	query atomic.Pointer[string]
+}
+
+// record remembers query if err is not nil.
+func (f *failedStatement) record(query string, err error) {
+	if f != nil && err != nil {
+		f.query.Store(&query)
+	}
+}
+
+// load returns the last failed query, or an empty string if none failed.
+func (f *failedStatement) load() string {
+	if f == nil {
+		return ""
+	}
+	if q := f.query.Load(); q != nil {
+		return *q
+	}
+	return ""
+}
+
+// -----------------------------
+// Statement Cache
+// -----------------------------
+
+// stmtCache holds the statements prepared within one transaction, keyed
+// by SQL text. They are closed by database/sql when the transaction ends.
+type stmtCache struct {
+	mu    sync.Mutex
+	stmts map[string]*sql.Stmt
+}
+
+// newStmtCache returns an empty statement cache.
+func newStmtCache() *stmtCache {
+	return &stmtCache{stmts: make(map[string]*sql.Stmt)}
+}
+
+// get returns the statement prepared for query on tx.
+func (c *stmtCache) get(ctx context.Context, tx *sql.Tx, query string) (*sql.Stmt, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if stmt, ok := c.stmts[query]; ok {
+		return stmt, true
+	}
+	stmt, err := tx.PrepareContext(ctx, query)
+	if err != nil {
+		return nil, false
+	}
+	c.stmts[query] = stmt
+	return stmt, true
+}
+
+// -----------------------------
+// Rows Affected
+// -----------------------------
+
+// TxRowsAffected returns the total number of rows affected by the
+// statements run so far in the transaction in the context.
+//
+// Only ExecContext calls made through PostgresQueryExecutor,
+// MySQLQueryExecutor or SQLiteQueryExecutor whose result reports
+// RowsAffected are counted; queries and statements issued directly on
+// the *sql.Tx are not. Nested calls and savepoints add to the total of
+// the root transaction, including work later rolled back to a savepoint.
+// It returns zero if the context carries no transaction.
+func TxRowsAffected(ctx context.Context) int64 {
+	scope, ok := scopeFromContext(ctx)
+	if !ok {
+		return 0
+	}
+	return scope.rowsAffected.Load()
+}
@@ -0,0 +1,367 @@
+package tx
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// InTxPostgres executes fn within a PostgreSQL transaction and returns
+// its value once the transaction has committed.
+//
+// It shares the semantics of WithPostgresDBTx: an existing transaction in
+// the context is reused, and the transaction is rolled back on error or
+// panic. The zero value of T is returned with any error, including a
+// failed commit.
+func InTxPostgres[T any](
+	r *BaseRepo,
+	ctx context.Context,
+	fn func(ctx context.Context) (T, error),
+) (T, error) {
+
+	var result T
+	err := r.WithPostgresDBTx(ctx, func(ctx context.Context) error {
+		v, err := fn(ctx)
+		if err != nil {
+			return err
+		}
+		result = v
+		return nil
+	})
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	return result, nil
+}
+
+// InTxTimescale executes fn within a TimescaleDB transaction and returns
+// its value once the transaction has committed.
+//
+// It shares the semantics of WithTimescaleDBTx: an existing transaction
+// in the context is reused, and the transaction is rolled back on error
+// or panic. The zero value of T is returned with any error, including a
+// failed commit.
+func InTxTimescale[T any](
+	r *BaseRepo,
+	ctx context.Context,
+	fn func(ctx context.Context) (T, error),
+) (T, error) {
+
+	var result T
+	err := r.WithTimescaleDBTx(ctx, func(ctx context.Context) error {
+		v, err := fn(ctx)
+		if err != nil {
+			return err
+		}
+		result = v
+		return nil
+	})
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	return result, nil
+}
+
+// InSavepointPostgres executes fn within a savepoint of the PostgreSQL
+// transaction in the context and returns its value once the savepoint
+// is released, as in "try this sub-operation and get a result or a
+// clean rollback".
+//
+// It shares the semantics of WithPostgresDBSavepoint: on error or panic
+// the savepoint is rolled back to and the outer transaction remains
+// usable, and the zero value of T is returned with the error. If no
+// transaction exists in the context, it behaves like InTxPostgres and fn
+// runs in a transaction of its own.
+func InSavepointPostgres[T any](
+	r *BaseRepo,
+	ctx context.Context,
+	fn func(ctx context.Context) (T, error),
+	opts ...SavepointOption,
+) (T, error) {
+
+	var result T
+	err := r.WithPostgresDBSavepoint(ctx, func(ctx context.Context) error {
+		v, err := fn(ctx)
+		if err != nil {
+			return err
+		}
+		result = v
+		return nil
+	}, opts...)
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	return result, nil
+}
+
+// ScanOne runs query with args through PostgresQueryExecutor and scans
+// the single resulting row with scan.
+//
+// The query runs in the transaction in the context if there is one. If
+// the query returns no rows, the error wraps sql.ErrNoRows; other errors
+// from the query or from scan are returned unchanged, along with the
+// zero value of T.
+func ScanOne[T any](
+	r *BaseRepo,
+	ctx context.Context,
+	query string,
+	args []any,
+	scan func(row *sql.Row) (T, error),
+) (T, error) {
+
+	v, err := scan(r.PostgresQueryExecutor(ctx).QueryRowContext(ctx, query, args...))
+	if err != nil {
+		var zero T
+		if errors.Is(err, sql.ErrNoRows) {
+			return zero, fmt.Errorf("tx: query returned no rows: %w", err)
+		}
+		return zero, err
+	}
+	return v, nil
+}
+
+// ExecInTx runs the statement query with args through
+// PostgresQueryExecutor and returns its result, so that one-off
+// statements, such as in migrations or scripts, need no repository
+// method. The statement runs in the transaction in the context if there
+// is one, and on the base *sql.DB otherwise:
+//
+//	err := r.WithPostgresDBTx(ctx, func(ctx context.Context) error {
+//		_, err := tx.ExecInTx(r, ctx, "UPDATE accounts SET active = false WHERE id = $1", id)
+//		return err
+//	})
+func ExecInTx(
+	r *BaseRepo,
+	ctx context.Context,
+	query string,
+	args ...any,
+) (sql.Result, error) {
+	return r.PostgresQueryExecutor(ctx).ExecContext(ctx, query, args...)
+}
+
+// ExecInTimescaleTx runs the statement query with args through
+// TimescaleQueryExecutor and returns its command tag. The statement runs
+// in the transaction in the context if there is one, and on the pool
+// otherwise:
+//
+//	err := r.WithTimescaleDBTx(ctx, func(ctx context.Context) error {
+//		tag, err := tx.ExecInTimescaleTx(r, ctx, "DELETE FROM metrics WHERE time < $1", cutoff)
+//		if err != nil {
+//			return err
+//		}
+//		log.Printf("deleted %d rows", tag.RowsAffected())
+//		return nil
+//	})
+func ExecInTimescaleTx(
+	r *BaseRepo,
+	ctx context.Context,
+	query string,
+	args ...any,
+) (pgconn.CommandTag, error) {
+	return r.TimescaleQueryExecutor(ctx).Exec(ctx, query, args...)
+}
+
+// StreamPostgresRows runs query with args within a PostgreSQL
+// transaction and calls fn for every resulting row as it arrives, for
+// result sets too large to buffer.
+//
+// The transaction in the context is reused if there is one; otherwise a
+// transaction is started for the duration of the stream and committed
+// once every row has been handled. fn must only scan the current row and
+// must not call Next or Close. Iteration stops at the first error from
+// fn, which is returned and rolls a transaction started here back. The
+// rows are closed in every case, and an error met while iterating is
+// returned.
+func StreamPostgresRows(
+	r *BaseRepo,
+	ctx context.Context,
+	query string,
+	args []any,
+	fn func(rows *sql.Rows) error,
+) error {
+
+	return r.WithPostgresDBTx(ctx, func(ctx context.Context) error {
+		rows, err := r.PostgresQueryExecutor(ctx).QueryContext(ctx, query, args...)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			if err := fn(rows); err != nil {
+				return err
+			}
+		}
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		return rows.Close()
+	})
+}
+
+// StreamTimescaleRows is the TimescaleDB counterpart of
+// StreamPostgresRows: it runs query with args within a TimescaleDB
+// transaction and calls fn for every row as it streams in.
+func StreamTimescaleRows(
+	r *BaseRepo,
+	ctx context.Context,
+	query string,
+	args []any,
+	fn func(rows pgx.Rows) error,
+) error {
+
+	return r.WithTimescaleDBTx(ctx, func(ctx context.Context) error {
+		rows, err := r.TimescaleQueryExecutor(ctx).Query(ctx, query, args...)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			if err := fn(rows); err != nil {
+				return err
+			}
+		}
+		rows.Close()
+		return rows.Err()
+	})
+}
+
+// EachOption configures RunEachInPostgresTx.
+type EachOption func(*eachConfig)
+
+// eachConfig holds the settings of RunEachInPostgresTx.
+type eachConfig struct {
+	maxConcurrency int
+}
+
+// MaxConcurrency runs up to n items of RunEachInPostgresTx in parallel,
+// each in its own transaction and connection. Keep n below the pool size
+// so other work can still get connections. Values below two run the items
+// one at a time, in order, which is the default.
+func MaxConcurrency(n int) EachOption {
+	return func(c *eachConfig) {
+		c.maxConcurrency = n
+	}
+}
+
+// RunEachInPostgresTx runs fn for every item, each in its own PostgreSQL
+// transaction, so that one failing item does not undo the others.
+//
+// The returned slice holds the error of each item at the same index, nil
+// for items that committed. Items run one at a time unless MaxConcurrency
+// is given. Once ctx is done, no further item is started: the remaining
+// ones report ctx.Err() and RunEachInPostgresTx returns as soon as the
+// items already running have finished.
+//
+// A panic in fn rolls back the item's transaction and, once the items
+// already running have finished, is re-raised in the caller.
+//
+// Each item gets a fresh transaction even when ctx already carries one,
+// as with WithoutPostgresTx: the items are independent of the caller's
+// transaction and commit on their own.
+func RunEachInPostgresTx[T any](
+	r *BaseRepo,
+	ctx context.Context,
+	items []T,
+	fn func(ctx context.Context, item T) error,
+	opts ...EachOption,
+) []error {
+
+	var cfg eachConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	errs := make([]error, len(items))
+	ctx = detachTx(ctx, r.keys.postgres)
+
+	run := func(i int) {
+		errs[i] = r.WithPostgresDBTx(ctx, func(ctx context.Context) error {
+			return fn(ctx, items[i])
+		})
+	}
+
+	// skip reports the remaining items as not run because ctx is done.
+	skip := func(from int) {
+		for i := from; i < len(items); i++ {
+			errs[i] = ctx.Err()
+		}
+	}
+
+	if cfg.maxConcurrency < 2 {
+		for i := range items {
+			if ctx.Err() != nil {
+				skip(i)
+				break
+			}
+			run(i)
+		}
+		return errs
+	}
+
+	// A panic in a worker would crash the process: the first one is
+	// re-raised on the caller's goroutine once all workers are done.
+	var wg sync.WaitGroup
+	var panicOnce sync.Once
+	var panicked any
+	sem := make(chan struct{}, cfg.maxConcurrency)
+launch:
+	for i := range items {
+		if ctx.Err() != nil {
+			skip(i)
+			break
+		}
+		select {
+		case <-ctx.Done():
+			skip(i)
+			break launch
+		case sem <- struct{}{}:
+		}
+
+		wg.Add(1)
+		go func() {
+			defer func() {
+				if p := recover(); p != nil {
+					panicOnce.Do(func() { panicked = p })
+				}
+				<-sem
+				wg.Done()
+			}()
+			run(i)
+		}()
+	}
+	wg.Wait()
+	if panicked != nil {
+		panic(panicked)
+	}
+	return errs
+}
+
+// TimescaleQueryAll runs query with args through TimescaleQueryExecutor
+// and collects every row into a T with pgx.RowToStructByName, matching
+// columns to struct fields by name or db tag.
+//
+// The query runs in the transaction in the context if there is one, and
+// on the pool otherwise. An empty result yields an empty slice.
+func TimescaleQueryAll[T any](
+	r *BaseRepo,
+	ctx context.Context,
+	query string,
+	args ...any,
+) ([]T, error) {
+
+	rows, err := r.TimescaleQueryExecutor(ctx).Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, pgx.RowToStructByName[T])
+}
@@ -0,0 +1,134 @@
+package tx_test
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+
+	"github.com/arunni/go-db-tx/tx"
+	"github.com/arunni/go-db-tx/tx/txtest"
+)
+
+func TestRunEachInPostgresTx(t *testing.T) {
+	spy := txtest.NewSpy()
+	r := tx.NewBaseRepo(spy.DB(), nil)
+	boom := errors.New("boom")
+
+	errs := tx.RunEachInPostgresTx(r, context.Background(), []int{1, 2, 3}, func(ctx context.Context, item int) error {
+		if item == 2 {
+			return boom
+		}
+		return nil
+	})
+
+	if errs[0] != nil || !errors.Is(errs[1], boom) || errs[2] != nil {
+		t.Fatalf("errs = %v, want only item 2 to fail", errs)
+	}
+	assertKinds(t, spy,
+		txtest.CallBegin, txtest.CallCommit,
+		txtest.CallBegin, txtest.CallRollback,
+		txtest.CallBegin, txtest.CallCommit,
+	)
+}
+
+func TestRunEachInPostgresTxDetachesCaller(t *testing.T) {
+	spy := txtest.NewSpy()
+	r := tx.NewBaseRepo(spy.DB(), nil)
+
+	var itemCommits atomic.Int32
+	err := r.WithPostgresDBTx(context.Background(), func(ctx context.Context) error {
+		errs := tx.RunEachInPostgresTx(r, ctx, []int{1, 2}, func(ctx context.Context, item int) error {
+			return tx.RegisterAfterCommit(ctx, func() { itemCommits.Add(1) })
+		})
+		if errs[0] != nil || errs[1] != nil {
+			t.Errorf("errs = %v", errs)
+		}
+		if n := itemCommits.Load(); n != 2 {
+			t.Errorf("item callbacks run before the caller commits = %d, want 2", n)
+		}
+		return tx.SetRollbackOnly(ctx)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assertKinds(t, spy,
+		txtest.CallBegin,
+		txtest.CallBegin, txtest.CallCommit,
+		txtest.CallBegin, txtest.CallCommit,
+		txtest.CallRollback,
+	)
+}
+
+func TestRunEachInPostgresTxConcurrent(t *testing.T) {
+	spy := txtest.NewSpy()
+	r := tx.NewBaseRepo(spy.DB(), nil)
+	boom := errors.New("boom")
+
+	items := make([]int, 20)
+	for i := range items {
+		items[i] = i
+	}
+	errs := tx.RunEachInPostgresTx(r, context.Background(), items, func(ctx context.Context, item int) error {
+		if item%5 == 0 {
+			return boom
+		}
+		return nil
+	}, tx.MaxConcurrency(4))
+
+	for i, err := range errs {
+		if want := i%5 == 0; errors.Is(err, boom) != want {
+			t.Fatalf("errs[%d] = %v", i, err)
+		}
+	}
+}
+
+func TestRunEachInPostgresTxCancelled(t *testing.T) {
+	r := tx.NewBaseRepo(txtest.NewSpy().DB(), nil)
+	ctx, cancel := context.WithCancel(context.Background())
+
+	errs := tx.RunEachInPostgresTx(r, ctx, []int{1, 2, 3}, func(ctx context.Context, item int) error {
+		if item == 1 {
+			cancel()
+		}
+		return nil
+	})
+
+	if !errors.Is(errs[0], context.Canceled) {
+		t.Errorf("errs[0] = %v, want the cancelled commit to fail", errs[0])
+	}
+	for _, err := range errs[1:] {
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("errs = %v, want the remaining items skipped", errs)
+		}
+	}
+}
+
+func TestRunEachInPostgresTxWorkerPanic(t *testing.T) {
+	spy := txtest.NewSpy()
+	r := tx.NewBaseRepo(spy.DB(), nil)
+
+	defer func() {
+		if p := recover(); p != "kaboom" {
+			t.Fatalf("recovered %v, want the worker's panic", p)
+		}
+		var rollbacks int
+		for _, c := range spy.Calls() {
+			if c.Kind == txtest.CallRollback {
+				rollbacks++
+			}
+		}
+		if rollbacks != 1 {
+			t.Fatalf("rollbacks = %d, want 1", rollbacks)
+		}
+	}()
+
+	tx.RunEachInPostgresTx(r, context.Background(), []int{1, 2, 3, 4}, func(ctx context.Context, item int) error {
+		if item == 3 {
+			panic("kaboom")
+		}
+		return nil
+	}, tx.MaxConcurrency(2))
+	t.Fatal("RunEachInPostgresTx returned")
+}
@@ -0,0 +1,60 @@
+package tx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// -----------------------------
+// Health Check
+// -----------------------------
+
+// HealthCheck verifies that every configured database accepts
+// connections and answers queries, for use in readiness probes.
+//
+// Each database is pinged and then asked to run SELECT 1, so a server
+// that accepts connections but cannot serve queries is reported too.
+// Unconfigured backends are skipped. The errors of all failing backends
+// are joined, each prefixed with the backend name.
+func (r *BaseRepo) HealthCheck(ctx context.Context) error {
+	var errs []error
+
+	for _, b := range []sqlBackend{r.postgres(), r.mysql(), r.sqlite()} {
+		if !b.configured() {
+			continue
+		}
+		if err := checkSQL(ctx, b); err != nil {
+			errs = append(errs, fmt.Errorf("tx: %s health check: %w", b.backend, err))
+		}
+	}
+
+	if r.timescaleDB != nil {
+		err := r.timescaleDB.Ping(ctx)
+		if err == nil {
+			_, err = r.timescaleDB.Exec(ctx, "SELECT 1")
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("tx: %s health check: %w", BackendTimescale, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// checkSQL pings the database of b and runs a trivial query on it. A
+// driver without a *sql.DB is only asked to run the query.
+func checkSQL(ctx context.Context, b sqlBackend) error {
+	if b.db != nil {
+		if err := b.db.PingContext(ctx); err != nil {
+			return err
+		}
+	}
+
+	var exec SQLExecutor = b.db
+	if b.driver != nil {
+		exec = b.driver
+	}
+	_, err := exec.ExecContext(ctx, "SELECT 1")
+	return err
+}
@@ -0,0 +1,32 @@
+package tx_test
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/arunni/go-db-tx/tx/txtest"
+)
+
+// assertKinds fails the test unless the spy recorded calls of exactly
+// the given kinds, in order.
+func assertKinds(t *testing.T, spy *txtest.Spy, want ...txtest.CallKind) {
+	t.Helper()
+
+	var got []txtest.CallKind
+	for _, c := range spy.Calls() {
+		got = append(got, c.Kind)
+	}
+	if !slices.Equal(got, want) {
+		t.Fatalf("calls = %v, want %v", got, want)
+	}
+}
+
+// assertQueries fails the test unless the spy recorded exactly the
+// given statements, in order.
+func assertQueries(t *testing.T, spy *txtest.Spy, want ...string) {
+	t.Helper()
+
+	if got := spy.Queries(); !slices.Equal(got, want) {
+		t.Fatalf("queries = %q, want %q", got, want)
+	}
+}
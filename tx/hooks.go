@@ -0,0 +1,55 @@
+package tx
+
+import (
+	"context"
+	"time"
+)
+
+// TxHook observes the lifecycle of the transactions started by a
+// repository.
+//
+// Hooks are a single extension point for custom logging, metrics or
+// tracing, without the package depending on any of them. They are called
+// synchronously, in registration order, for transactions the repository
+// starts; reused transactions and savepoints are not reported.
+// Implementations must be safe for concurrent use.
+//
+// ctx is the context passed to the transaction function: it carries
+// the transaction itself, its TxStore and label, and the values of the
+// caller's context, such as a request ID or the active trace span, so
+// that hooks can correlate their records with the caller's.
+type TxHook interface {
+	// OnBegin is called once a transaction has begun.
+	OnBegin(ctx context.Context, backend Backend)
+
+	// OnCommit is called once a transaction has committed, with the time
+	// elapsed since it began.
+	OnCommit(ctx context.Context, backend Backend, d time.Duration)
+
+	// OnRollback is called once a transaction has been rolled back,
+	// including when its commit failed, with the time elapsed since it
+	// began and the error that caused the rollback.
+	OnRollback(ctx context.Context, backend Backend, d time.Duration, err error)
+}
+
+// RetryHook is implemented by a TxHook that also wants to know when a
+// retried transaction, see WithPostgresDBTxRetry, gave up because every
+// attempt failed with a retryable error. Such exhaustion usually points
+// at a contention hotspot worth alerting on.
+type RetryHook interface {
+	// OnRetryExhausted is called with the number of attempts made and
+	// the error of the last one.
+	OnRetryExhausted(ctx context.Context, backend Backend, attempts int, err error)
+}
+
+// WithHooks registers hooks notified of every transaction the
+// repository starts. It may be given several times; hooks accumulate.
+func WithHooks(hooks ...TxHook) Option {
+	return func(r *BaseRepo) {
+		for _, h := range hooks {
+			if h != nil {
+				r.hooks = append(r.hooks, h)
+			}
+		}
+	}
+}
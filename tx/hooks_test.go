@@ -0,0 +1,67 @@
+package tx_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/arunni/go-db-tx/tx"
+	"github.com/arunni/go-db-tx/tx/txtest"
+)
+
+// panicHook is a TxHook that panics when a transaction begins.
+type panicHook struct{}
+
+func (panicHook) OnBegin(context.Context, tx.Backend)                          { panic("kaboom") }
+func (panicHook) OnCommit(context.Context, tx.Backend, time.Duration)          {}
+func (panicHook) OnRollback(context.Context, tx.Backend, time.Duration, error) {}
+
+// commitPanicHook is a TxHook that panics when a transaction commits.
+type commitPanicHook struct{}
+
+func (commitPanicHook) OnBegin(context.Context, tx.Backend)                          {}
+func (commitPanicHook) OnCommit(context.Context, tx.Backend, time.Duration)          { panic("kaboom") }
+func (commitPanicHook) OnRollback(context.Context, tx.Backend, time.Duration, error) {}
+
+// recordingHook is a TxHook that records the lifecycle events it sees.
+type recordingHook struct {
+	mu   sync.Mutex
+	seen []string
+}
+
+func (h *recordingHook) record(event string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.seen = append(h.seen, event)
+}
+
+func (h *recordingHook) events() []string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return append([]string(nil), h.seen...)
+}
+
+func (h *recordingHook) OnBegin(context.Context, tx.Backend)                 { h.record("begin") }
+func (h *recordingHook) OnCommit(context.Context, tx.Backend, time.Duration) { h.record("commit") }
+func (h *recordingHook) OnRollback(context.Context, tx.Backend, time.Duration, error) {
+	h.record("rollback")
+}
+
+func TestPanicInBeginHookRollsBack(t *testing.T) {
+	spy := txtest.NewSpy()
+	r := tx.NewBaseRepo(spy.DB(), nil, tx.RecoverPanics(true), tx.WithHooks(panicHook{}))
+
+	called := false
+	err := r.WithPostgresDBTx(context.Background(), func(ctx context.Context) error {
+		called = true
+		return nil
+	})
+
+	var perr *tx.PanicError
+	if !errors.As(err, &perr) || called {
+		t.Fatalf("err = %v, called = %v, want a PanicError without calling fn", err, called)
+	}
+	assertKinds(t, spy, txtest.CallBegin, txtest.CallRollback)
+}
@@ -0,0 +1,70 @@
+package tx
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrAlreadyProcessed is returned by WithPostgresDBTxIdempotent when the
+// idempotency key was already recorded, meaning fn has run before.
+var ErrAlreadyProcessed = errors.New("tx: operation already processed")
+
+// defaultIdempotencyTable is the dedup table used unless changed with
+// WithIdempotencyTable.
+const defaultIdempotencyTable = "tx_idempotency_keys"
+
+// -----------------------------
+// Idempotent Transactions
+// -----------------------------
+
+// WithPostgresDBTxIdempotent executes the given function within a
+// PostgreSQL transaction at most once per key.
+//
+// Within the transaction, key is inserted into the dedup table before fn
+// runs. If the key is already present, fn is skipped, the transaction is
+// committed and ErrAlreadyProcessed is returned; otherwise fn runs and the
+// key is committed or rolled back together with its work, so a failed
+// attempt can be retried. Concurrent calls with the same key wait for each
+// other on the key's row.
+//
+// The table, "tx_idempotency_keys" unless set with WithIdempotencyTable,
+// must have a unique idempotency_key text column:
+//
+//	CREATE TABLE tx_idempotency_keys (
+//		idempotency_key text PRIMARY KEY,
+//		processed_at    timestamptz NOT NULL DEFAULT now()
+//	);
+//
+// If a transaction already exists in the context, the key is recorded in
+// it and only becomes permanent once that transaction commits.
+func (r *BaseRepo) WithPostgresDBTxIdempotent(
+	ctx context.Context,
+	key string,
+	fn func(ctx context.Context) error,
+) error {
+
+	query := "INSERT INTO " + r.idempotencyTable + " (idempotency_key) VALUES ($1) ON CONFLICT DO NOTHING"
+
+	var processed bool
+	err := r.WithPostgresDBTx(ctx, func(ctx context.Context) error {
+		// Only the attempt that commits decides, under a default retry.
+		processed = false
+		res, err := r.PostgresQueryExecutor(ctx).ExecContext(ctx, query, key)
+		if err != nil {
+			return err
+		}
+		n, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			processed = true
+			return nil
+		}
+		return fn(ctx)
+	})
+	if err == nil && processed {
+		return ErrAlreadyProcessed
+	}
+	return err
+}
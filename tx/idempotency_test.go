@@ -0,0 +1,91 @@
+package tx_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/arunni/go-db-tx/tx"
+	"github.com/arunni/go-db-tx/tx/txtest"
+)
+
+const insertKey = "INSERT INTO tx_idempotency_keys (idempotency_key) VALUES ($1) ON CONFLICT DO NOTHING"
+
+func TestIdempotentTx(t *testing.T) {
+	spy := txtest.NewSpy()
+	r := tx.NewBaseRepo(spy.DB(), nil)
+
+	calls := 0
+	fn := func(ctx context.Context) error {
+		calls++
+		return nil
+	}
+
+	spy.StubExec(insertKey, 1)
+	if err := r.WithPostgresDBTxIdempotent(context.Background(), "order-1", fn); err != nil {
+		t.Fatal(err)
+	}
+	spy.StubExec(insertKey, 0)
+	if err := r.WithPostgresDBTxIdempotent(context.Background(), "order-1", fn); !errors.Is(err, tx.ErrAlreadyProcessed) {
+		t.Fatalf("err = %v, want ErrAlreadyProcessed", err)
+	}
+	if calls != 1 {
+		t.Fatalf("fn calls = %d, want 1", calls)
+	}
+	if args := spy.Calls()[1].Args; len(args) != 1 || args[0] != "order-1" {
+		t.Fatalf("key args = %v, want [order-1]", args)
+	}
+}
+
+// commitFailDriver is a tx.SQLDriver whose first commit fails with a
+// serialization failure, after calling beforeFail.
+type commitFailDriver struct {
+	*sql.DB
+	failed     bool
+	beforeFail func()
+}
+
+func (d *commitFailDriver) BeginTx(ctx context.Context, opts *sql.TxOptions) (tx.SQLTx, error) {
+	sqlTx, err := d.DB.BeginTx(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &commitFailTx{Tx: sqlTx, d: d}, nil
+}
+
+type commitFailTx struct {
+	*sql.Tx
+	d *commitFailDriver
+}
+
+func (t *commitFailTx) Commit() error {
+	if !t.d.failed {
+		t.d.failed = true
+		t.d.beforeFail()
+		_ = t.Tx.Rollback()
+		return errSerialization
+	}
+	return t.Tx.Commit()
+}
+
+func TestIdempotentTxRetried(t *testing.T) {
+	spy := txtest.NewSpy()
+	driver := &commitFailDriver{DB: spy.DB(), beforeFail: func() { spy.StubExec(insertKey, 1) }}
+	r := tx.NewBaseRepoWithDrivers(driver, nil,
+		tx.WithClock(&sleepClock{}),
+		tx.WithDefaultPostgresRetry(&tx.RetryConfig{MaxAttempts: 2}),
+	)
+
+	// The first attempt sees the key, as if another process held it, and
+	// fails to commit; the second one records it and runs fn.
+	spy.StubExec(insertKey, 0)
+	calls := 0
+	err := r.WithPostgresDBTxIdempotent(context.Background(), "order-1", func(ctx context.Context) error {
+		calls++
+		return nil
+	})
+	if err != nil || calls != 1 {
+		t.Fatalf("err = %v, fn calls = %d, want nil after running fn once", err, calls)
+	}
+}
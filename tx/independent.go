@@ -0,0 +1,67 @@
+package tx
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrAutonomousTxStarvation is returned by WithPostgresAutonomousTx when
+// the pool is limited to a single connection, which the surrounding
+// transaction already holds.
+var ErrAutonomousTxStarvation = errors.New("tx: autonomous transaction needs a second connection but the pool allows only one")
+
+// -----------------------------
+// Independent Transactions
+// -----------------------------
+
+// WithPostgresDBTxNew executes the given function within a new PostgreSQL
+// transaction, even if one is already active in the context.
+//
+// The new transaction runs on its own connection from the pool and
+// replaces the active one only in the context passed to fn; callbacks,
+// TxStore and IsOutermostTx inside fn refer to the new transaction. This
+// breaks atomicity with the surrounding transaction by design: the new
+// transaction commits or rolls back on its own, and its outcome is not
+// undone if the surrounding transaction later rolls back.
+//
+// Both transactions are separate sessions, so fn must not wait on locks
+// held by the surrounding transaction, such as by updating rows it has
+// modified, or it blocks forever.
+func (r *BaseRepo) WithPostgresDBTxNew(
+	ctx context.Context,
+	fn func(ctx context.Context) error,
+) error {
+	return r.WithPostgresDBTx(detachTx(ctx, r.keys.postgres), fn)
+}
+
+// WithPostgresAutonomousTx executes the given function within an
+// autonomous PostgreSQL transaction, which commits independently of the
+// surrounding transaction, so that audit or error-log rows written by fn
+// survive even if the business transaction rolls back.
+//
+// The autonomous transaction runs on a second connection acquired from
+// the pool, never on the one holding the surrounding transaction, so the
+// pool must be able to hand out one more connection while the caller's
+// is held. A pool limited to a single connection with SetMaxOpenConns(1)
+// would wait forever and is rejected with ErrAutonomousTxStarvation; on a
+// saturated pool, BeginTimeout keeps the wait bounded. As with
+// WithPostgresDBTxNew, fn must not wait on locks held by the surrounding
+// transaction.
+func (r *BaseRepo) WithPostgresAutonomousTx(
+	ctx context.Context,
+	fn func(ctx context.Context) error,
+) error {
+
+	if r.IsInPostgresTx(ctx) && r.postgresDB != nil && r.postgresDB.Stats().MaxOpenConnections == 1 {
+		return ErrAutonomousTxStarvation
+	}
+	return r.WithPostgresDBTxNew(ctx, fn)
+}
+
+// detachTx returns a context without the transaction stored under
+// key and without the scope of the surrounding transaction, so that a new
+// transaction started on it is independent.
+func detachTx(ctx context.Context, key contextKey) context.Context {
+	ctx = context.WithValue(ctx, key, nil)
+	return context.WithValue(ctx, scopeKey, (*txScope)(nil))
+}
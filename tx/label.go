@@ -0,0 +1,47 @@
+package tx
+
+import (
+	"context"
+	"fmt"
+)
+
+// labelKey stores the label of the current unit of work.
+const labelKey contextKey = "tx_label"
+
+// TxLabel returns the label attached to the transaction in the context
+// by WithPostgresDBTxLabeled, if any.
+func TxLabel(ctx context.Context) (string, bool) {
+	label, ok := ctx.Value(labelKey).(string)
+	return label, ok
+}
+
+// WithPostgresDBTxLabeled executes the given function within a PostgreSQL
+// transaction tagged with label, such as a request ID.
+//
+// The label is stored in the context, included in the lifecycle logs and
+// the trace span of the transaction, and prefixed to the error returned
+// when the transaction fails. Nested calls, including nested labeled
+// calls, keep the label of the outermost one so the whole unit of work
+// shares it.
+//
+// With LabelApplicationName enabled, application_name is set to the label
+// for the duration of the transaction; txOpts, such as ApplicationName,
+// configure the transaction per call as with WithPostgresDBTxOpts.
+func (r *BaseRepo) WithPostgresDBTxLabeled(
+	ctx context.Context,
+	label string,
+	fn func(ctx context.Context) error,
+	txOpts ...TxOption,
+) error {
+
+	if _, ok := TxLabel(ctx); ok {
+		return r.WithPostgresDBTxOpts(ctx, nil, fn, txOpts...)
+	}
+
+	started := !r.IsInPostgresTx(ctx)
+	err := r.WithPostgresDBTxOpts(context.WithValue(ctx, labelKey, label), nil, fn, txOpts...)
+	if err != nil && started {
+		return fmt.Errorf("tx %q: %w", label, err)
+	}
+	return err
+}
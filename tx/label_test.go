@@ -0,0 +1,70 @@
+package tx_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/arunni/go-db-tx/tx"
+	"github.com/arunni/go-db-tx/tx/txtest"
+)
+
+func TestLabeledTx(t *testing.T) {
+	spy := txtest.NewSpy()
+	r := tx.NewBaseRepo(spy.DB(), nil)
+
+	err := r.WithPostgresDBTxLabeled(context.Background(), "req-1", func(ctx context.Context) error {
+		return r.WithPostgresDBTxLabeled(ctx, "req-2", func(ctx context.Context) error {
+			if label, ok := tx.TxLabel(ctx); !ok || label != "req-1" {
+				t.Fatalf("TxLabel = %q, %v, want the outermost label req-1", label, ok)
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertKinds(t, spy, txtest.CallBegin, txtest.CallCommit)
+}
+
+func TestLabeledTxError(t *testing.T) {
+	spy := txtest.NewSpy()
+	r := tx.NewBaseRepo(spy.DB(), nil)
+	boom := errors.New("boom")
+
+	err := r.WithPostgresDBTxLabeled(context.Background(), "req-1", func(ctx context.Context) error {
+		return r.WithPostgresDBTxLabeled(ctx, "req-2", func(context.Context) error { return boom })
+	})
+	if !errors.Is(err, boom) || !strings.HasPrefix(err.Error(), `tx "req-1": `) || strings.Count(err.Error(), "req-") != 1 {
+		t.Fatalf("err = %v, want boom prefixed once with the outermost label", err)
+	}
+}
+
+func TestLabelApplicationName(t *testing.T) {
+	tests := []struct {
+		name    string
+		enabled bool
+		opts    []tx.TxOption
+		want    []string
+	}{
+		{"disabled", false, nil, nil},
+		{"from label", true, nil, []string{"SET LOCAL application_name = 'it''s req-1'"}},
+		{"per call takes precedence", true, []tx.TxOption{tx.ApplicationName("reports")}, []string{"SET LOCAL application_name = 'reports'"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spy := txtest.NewSpy()
+			r := tx.NewBaseRepo(spy.DB(), nil, tx.LabelApplicationName(tt.enabled))
+
+			err := r.WithPostgresDBTxLabeled(context.Background(), "it's req-1", func(context.Context) error {
+				return nil
+			}, tt.opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			assertQueries(t, spy, tt.want...)
+		})
+	}
+}
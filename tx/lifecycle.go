@@ -0,0 +1,143 @@
+package tx
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// txRun reports the lifecycle of a single transaction started by the
+// repository to the configured instrumentation.
+type txRun struct {
+	r       *BaseRepo
+	ctx     context.Context
+	backend Backend
+	label   string
+	span    trace.Span
+	start   time.Time
+
+	// failed is set when the statements failing within the transaction
+	// are recorded for the rollback log.
+	failed *failedStatement
+}
+
+// startRun is called right before a transaction begins. The returned
+// context must be used to begin the transaction.
+func (r *BaseRepo) startRun(ctx context.Context, backend Backend) (context.Context, *txRun) {
+	label, _ := TxLabel(ctx)
+	ctx, span := r.startSpan(ctx, backend, label)
+	return ctx, &txRun{r: r, ctx: ctx, backend: backend, label: label, span: span}
+}
+
+// log emits a lifecycle record carrying the backend and label.
+func (t *txRun) log(level slog.Level, msg string, attrs ...slog.Attr) {
+	base := []slog.Attr{slog.String("backend", string(t.backend))}
+	if t.label != "" {
+		base = append(base, slog.String("label", t.label))
+	}
+	t.r.logger.LogAttrs(t.ctx, level, msg, append(base, attrs...)...)
+}
+
+// txLogger returns the repository logger enriched with the metadata of
+// the transaction. It must be called once the transaction has begun.
+func (t *txRun) txLogger() *slog.Logger {
+	attrs := []any{slog.String("backend", string(t.backend))}
+	if t.label != "" {
+		attrs = append(attrs, slog.String("label", t.label))
+	}
+	attrs = append(attrs, slog.Time("tx_started_at", t.start))
+	return t.r.logger.With(attrs...)
+}
+
+// begun is called once the transaction has begun.
+func (t *txRun) begun() {
+	t.start = t.r.clock.Now()
+	t.r.metrics.IncStarted(t.backend)
+	t.log(slog.LevelDebug, "transaction begun")
+}
+
+// bind is called with the context carrying the transaction once it is
+// built, before fn runs. Hooks and log records from then on receive it.
+func (t *txRun) bind(txCtx context.Context) {
+	t.ctx = txCtx
+	for _, h := range t.r.hooks {
+		h.OnBegin(t.ctx, t.backend)
+	}
+	t.checkNested()
+}
+
+// beginFailed is called when the transaction could not be started.
+func (t *txRun) beginFailed(err error) {
+	t.log(slog.LevelWarn, "transaction begin failed", slog.Any("error", err))
+	endSpan(t.span, err)
+}
+
+// committed is called once the transaction has committed.
+func (t *txRun) committed() {
+	elapsed := t.r.clock.Now().Sub(t.start)
+	t.r.metrics.IncCommitted(t.backend)
+	t.r.metrics.ObserveDuration(t.backend, elapsed)
+	t.log(slog.LevelDebug, "transaction committed", slog.Duration("duration", elapsed))
+	t.checkSlow(elapsed)
+	for _, h := range t.r.hooks {
+		h.OnCommit(t.ctx, t.backend, elapsed)
+	}
+	endSpan(t.span, nil)
+}
+
+// rolledBack is called once the transaction has been rolled back
+// because of cause. rbErr is the error returned by the rollback itself,
+// if any.
+func (t *txRun) rolledBack(cause, rbErr error) {
+	elapsed := t.r.clock.Now().Sub(t.start)
+	t.r.metrics.IncRolledBack(t.backend)
+	t.r.metrics.ObserveDuration(t.backend, elapsed)
+	attrs := []slog.Attr{
+		slog.Duration("duration", elapsed),
+		slog.Any("error", cause),
+	}
+	if query := t.failed.load(); query != "" {
+		attrs = append(attrs, slog.String("last_failed_query", query))
+	}
+	t.log(slog.LevelWarn, "transaction rolled back", attrs...)
+	if rbErr != nil {
+		t.log(slog.LevelError, "transaction rollback failed", slog.Any("error", rbErr))
+	}
+	t.checkSlow(elapsed)
+	err := errors.Join(cause, rbErr)
+	for _, h := range t.r.hooks {
+		h.OnRollback(t.ctx, t.backend, elapsed, err)
+	}
+	endSpan(t.span, err)
+}
+
+// inDoubt is called when a prepared side of a two-phase transaction
+// could not be committed and is left to ResolveInDoubtTransactions. Hooks
+// see it as a rollback with err.
+func (t *txRun) inDoubt(err error) {
+	elapsed := t.r.clock.Now().Sub(t.start)
+	t.log(slog.LevelError, "prepared transaction left in doubt",
+		slog.Duration("duration", elapsed),
+		slog.Any("error", err),
+	)
+	for _, h := range t.r.hooks {
+		h.OnRollback(t.ctx, t.backend, elapsed, err)
+	}
+	endSpan(t.span, err)
+}
+
+// checkSlow warns about a finished transaction that stayed open longer
+// than the configured slow transaction threshold.
+func (t *txRun) checkSlow(elapsed time.Duration) {
+	threshold := t.r.slowTxThreshold
+	if threshold <= 0 || elapsed <= threshold {
+		return
+	}
+	t.log(slog.LevelWarn, "slow transaction",
+		slog.Duration("duration", elapsed),
+		slog.Duration("threshold", threshold),
+	)
+}
@@ -0,0 +1,54 @@
+package tx_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/arunni/go-db-tx/tx"
+	"github.com/arunni/go-db-tx/tx/txtest"
+)
+
+func TestMaxTxLifetime(t *testing.T) {
+	spy := txtest.NewSpy()
+	r := tx.NewBaseRepo(spy.DB(), nil, tx.MaxTxLifetime(20*time.Millisecond))
+
+	err := r.WithPostgresDBTx(context.Background(), func(ctx context.Context) error {
+		<-ctx.Done()
+		return nil
+	})
+	if !errors.Is(err, tx.ErrTxLifetimeExceeded) {
+		t.Fatalf("err = %v, want ErrTxLifetimeExceeded", err)
+	}
+	if calls := spy.Calls(); calls[len(calls)-1].Kind == txtest.CallCommit {
+		t.Fatal("expired transaction was committed")
+	}
+}
+
+func TestMaxTxLifetimeNotReached(t *testing.T) {
+	spy := txtest.NewSpy()
+	r := tx.NewBaseRepo(spy.DB(), nil, tx.MaxTxLifetime(time.Second))
+
+	err := r.WithPostgresDBTx(context.Background(), func(context.Context) error { return nil })
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertKinds(t, spy, txtest.CallBegin, txtest.CallCommit)
+}
+
+func TestMaxTxLifetimeCallerCancellation(t *testing.T) {
+	spy := txtest.NewSpy()
+	r := tx.NewBaseRepo(spy.DB(), nil, tx.MaxTxLifetime(20*time.Millisecond))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	err := r.WithPostgresDBTx(ctx, func(txCtx context.Context) error {
+		cancel()
+		<-txCtx.Done()
+		return txCtx.Err()
+	})
+	if err == nil || errors.Is(err, tx.ErrTxLifetimeExceeded) {
+		t.Fatalf("err = %v, want the caller's cancellation", err)
+	}
+}
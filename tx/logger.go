@@ -0,0 +1,28 @@
+package tx
+
+import (
+	"context"
+	"log/slog"
+)
+
+// loggerKey stores the logger of the innermost transaction started by a
+// repository.
+const loggerKey contextKey = "tx_logger"
+
+// discardLogger is returned by LoggerFromTx outside a transaction.
+var discardLogger = slog.New(slog.DiscardHandler)
+
+// LoggerFromTx returns the logger configured with WithLogger, enriched
+// with the backend, label and start time of the transaction in the
+// context, so that log lines written inside the transaction are
+// correlated with it.
+//
+// Nested calls and savepoints get the logger of the transaction they
+// reuse. Outside a transaction, or when no logger is configured, the
+// returned logger discards everything.
+func LoggerFromTx(ctx context.Context) *slog.Logger {
+	if logger, ok := ctx.Value(loggerKey).(*slog.Logger); ok {
+		return logger
+	}
+	return discardLogger
+}
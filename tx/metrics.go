@@ -0,0 +1,37 @@
+package tx
+
+import "time"
+
+// TxMetrics receives transaction lifecycle measurements.
+//
+// It keeps the package independent of any metrics library: implement it
+// on top of Prometheus, OpenTelemetry metrics or anything else, labelling
+// each measurement by backend. Implementations must be safe for
+// concurrent use.
+type TxMetrics interface {
+	// IncStarted is called when a transaction has begun.
+	IncStarted(backend Backend)
+
+	// IncCommitted is called when a transaction has committed.
+	IncCommitted(backend Backend)
+
+	// IncRolledBack is called when a transaction has been rolled back,
+	// including when its commit failed.
+	IncRolledBack(backend Backend)
+
+	// IncRetried is called each time a failed transaction is retried.
+	IncRetried(backend Backend)
+
+	// ObserveDuration records the time between begin and the end of
+	// a transaction, whatever its outcome.
+	ObserveDuration(backend Backend, d time.Duration)
+}
+
+// noopMetrics is the TxMetrics used when none is configured.
+type noopMetrics struct{}
+
+func (noopMetrics) IncStarted(Backend)                     {}
+func (noopMetrics) IncCommitted(Backend)                   {}
+func (noopMetrics) IncRolledBack(Backend)                  {}
+func (noopMetrics) IncRetried(Backend)                     {}
+func (noopMetrics) ObserveDuration(Backend, time.Duration) {}
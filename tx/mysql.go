@@ -0,0 +1,50 @@
+package tx
+
+import (
+	"context"
+	"database/sql"
+)
+
+// -----------------------------
+// MySQL Transaction
+// -----------------------------
+
+// WithMySQLDBTx executes the given function within a MySQL transaction.
+//
+// It requires a MySQL database configured with WithMySQLDB. If
+// a transaction already exists in the context, it will be reused.
+// The transaction is automatically committed on success or rolled
+// back on error or panic.
+func (r *BaseRepo) WithMySQLDBTx(
+	ctx context.Context,
+	fn func(ctx context.Context) error,
+) error {
+	return r.withSQLTx(ctx, r.mysql(), nil, txConfig{}, fn)
+}
+
+// mysql returns the MySQL database/sql backend.
+func (r *BaseRepo) mysql() sqlBackend {
+	return sqlBackend{backend: BackendMySQL, db: r.mysqlDB, key: r.keys.mysql}
+}
+
+// GetMySQLTxFromContext retrieves a MySQL transaction from the context.
+func (r *BaseRepo) GetMySQLTxFromContext(ctx context.Context) (*sql.Tx, bool) {
+	active, ok := sqlTxFromContext(ctx, r.keys.mysql)
+	if !ok {
+		return nil, false
+	}
+	tx, ok := active.tx.(*sql.Tx)
+	return tx, ok
+}
+
+// MySQLQueryExecutor returns a MySQL query executor.
+//
+// If a transaction exists in the context, statements run in it.
+// Otherwise, the MySQL *sql.DB instance is used.
+func (r *BaseRepo) MySQLQueryExecutor(ctx context.Context) SQLExecutor {
+	if active, ok := sqlTxFromContext(ctx, r.keys.mysql); ok {
+		return newTxExecutor(active)
+	}
+	r.checkContext(ctx, BackendMySQL, r.keys.mysql)
+	return r.mysqlDB
+}
@@ -0,0 +1,90 @@
+package tx
+
+import (
+	"context"
+	"log/slog"
+)
+
+// -----------------------------
+// Cross-Backend Nesting
+// -----------------------------
+
+// nestingKey stores the backend whose transaction is deliberately
+// started within a transaction of another backend, as by WithDualTx.
+const nestingKey contextKey = "tx_nesting"
+
+// NestedTxHook is implemented by a TxHook that also wants to know when
+// a transaction begins while transactions of other backends are open in
+// its context, for example to audit code that relies on the two
+// committing together.
+type NestedTxHook interface {
+	// OnNestedBegin is called after OnBegin with the backend of the new
+	// transaction and the backends of the transactions already open.
+	OnNestedBegin(ctx context.Context, backend Backend, open []Backend)
+}
+
+// WarnCrossBackendTx logs a warning, with the backend and the backends
+// already open, for every transaction that begins while a transaction of
+// another backend is open in the context. Such nesting is sometimes
+// intended, but it often comes from calling the helper of the wrong
+// backend, and the two transactions never commit atomically. WithDualTx
+// and WithTwoPhaseCommit nest on purpose and are not reported. It is
+// disabled by default; see NestedTxHook to observe nesting instead.
+func WarnCrossBackendTx(enabled bool) Option {
+	return func(r *BaseRepo) {
+		r.warnCrossBackend = enabled
+	}
+}
+
+// withIntendedNesting marks backend's transaction started on ctx as
+// deliberately nested within the transactions open in ctx.
+func withIntendedNesting(ctx context.Context, backend Backend) context.Context {
+	return context.WithValue(ctx, nestingKey, backend)
+}
+
+// openBackends returns the backends, other than except, whose
+// transactions of this repository are open in ctx.
+func (r *BaseRepo) openBackends(ctx context.Context, except Backend) []Backend {
+	var open []Backend
+	add := func(backend Backend, ok bool) {
+		if ok && backend != except {
+			open = append(open, backend)
+		}
+	}
+
+	_, ok := sqlTxFromContext(ctx, r.keys.postgres)
+	add(BackendPostgres, ok)
+	_, ok = r.timescaleTxFromContext(ctx)
+	add(BackendTimescale, ok)
+	_, ok = sqlTxFromContext(ctx, r.keys.mysql)
+	add(BackendMySQL, ok)
+	_, ok = sqlTxFromContext(ctx, r.keys.sqlite)
+	add(BackendSQLite, ok)
+	return open
+}
+
+// checkNested reports a transaction that began within transactions of
+// other backends to the NestedTxHook hooks and, unless the nesting is
+// intended, to the log.
+func (t *txRun) checkNested() {
+	open := t.r.openBackends(t.ctx, t.backend)
+	if len(open) == 0 {
+		return
+	}
+
+	intended := t.ctx.Value(nestingKey) == t.backend
+	if t.r.warnCrossBackend && !intended {
+		names := make([]string, len(open))
+		for i, backend := range open {
+			names[i] = string(backend)
+		}
+		t.log(slog.LevelWarn, "transaction begun within a transaction of another backend",
+			slog.Any("open_backends", names),
+		)
+	}
+	for _, h := range t.r.hooks {
+		if nh, ok := h.(NestedTxHook); ok {
+			nh.OnNestedBegin(t.ctx, t.backend, open)
+		}
+	}
+}
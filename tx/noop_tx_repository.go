@@ -0,0 +1,30 @@
+package tx
+
+import "context"
+
+// NoopTxRepository is a TxRepository that performs no database work: its
+// methods call fn directly with the given context.
+//
+// It lets usecases that expect a TxRepository run in configurations where
+// they do not touch a database, such as components backed by an external
+// API. Nothing is committed or rolled back.
+type NoopTxRepository struct{}
+
+// Compile-time assertion to ensure NoopTxRepository implements TxRepository.
+var _ TxRepository = NoopTxRepository{}
+
+// WithPostgresDBTx calls fn with ctx.
+func (NoopTxRepository) WithPostgresDBTx(
+	ctx context.Context,
+	fn func(ctx context.Context) error,
+) error {
+	return fn(ctx)
+}
+
+// WithTimescaleDBTx calls fn with ctx.
+func (NoopTxRepository) WithTimescaleDBTx(
+	ctx context.Context,
+	fn func(ctx context.Context) error,
+) error {
+	return fn(ctx)
+}
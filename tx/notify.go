@@ -0,0 +1,27 @@
+package tx
+
+import "context"
+
+// -----------------------------
+// PostgreSQL Notifications
+// -----------------------------
+
+// NotifyOnCommit sends a notification on channel with the given payload
+// through the PostgreSQL transaction in the context.
+//
+// PostgreSQL buffers notifications issued inside a transaction and only
+// delivers them once it commits; if the transaction rolls back, the
+// notification is discarded. The notification is sent with pg_notify, so
+// channel and payload are passed as parameters rather than interpolated.
+//
+// ErrNoTransaction is returned if the context carries no PostgreSQL
+// transaction, since the notification would otherwise be sent at once.
+func (r *BaseRepo) NotifyOnCommit(ctx context.Context, channel, payload string) error {
+	tx, ok := r.postgresTx(ctx)
+	if !ok {
+		return ErrNoTransaction
+	}
+
+	_, err := tx.ExecContext(ctx, "SELECT pg_notify($1, $2)", channel, payload)
+	return err
+}
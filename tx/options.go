@@ -0,0 +1,294 @@
+package tx
+
+import (
+	"database/sql"
+	"log/slog"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Option configures optional BaseRepo behaviour in NewBaseRepo.
+type Option func(*BaseRepo)
+
+// WithNamespace stores this repository's transactions under context keys
+// distinct to namespace.
+//
+// By default every BaseRepo shares the same keys, so repositories built
+// on the same databases join each other's transactions. Give each
+// BaseRepo that targets a different cluster its own namespace so that a
+// transaction from one cluster is never picked up by another.
+func WithNamespace(namespace string) Option {
+	return func(r *BaseRepo) {
+		r.keys = newTxKeys(namespace)
+	}
+}
+
+// WithTracer sets the OpenTelemetry tracer used to record a span for
+// every transaction started by the repository.
+//
+// When no tracer is provided, a no-op tracer is used.
+func WithTracer(tracer trace.Tracer) Option {
+	return func(r *BaseRepo) {
+		if tracer != nil {
+			r.tracer = tracer
+		}
+	}
+}
+
+// WithMetrics sets the TxMetrics that receives transaction counts and
+// durations, labelled by backend.
+//
+// When no metrics are provided, measurements are discarded.
+func WithMetrics(metrics TxMetrics) Option {
+	return func(r *BaseRepo) {
+		if metrics != nil {
+			r.metrics = metrics
+		}
+	}
+}
+
+// WithLogger sets the logger that records the transaction lifecycle.
+//
+// Begin and commit are logged at debug level with the backend and
+// duration; rollbacks are logged at warn level with the triggering
+// error. When no logger is provided, nothing is logged.
+func WithLogger(logger *slog.Logger) Option {
+	return func(r *BaseRepo) {
+		if logger != nil {
+			r.logger = logger
+		}
+	}
+}
+
+// WithMySQLDB sets the *sql.DB used for MySQL transactions, letting one
+// BaseRepo coordinate repositories spanning PostgreSQL and MySQL.
+func WithMySQLDB(db *sql.DB) Option {
+	return func(r *BaseRepo) {
+		r.mysqlDB = db
+	}
+}
+
+// WithSQLiteDB sets the *sql.DB used for SQLite transactions, typically
+// for local development and tests.
+func WithSQLiteDB(db *sql.DB) Option {
+	return func(r *BaseRepo) {
+		r.sqliteDB = db
+	}
+}
+
+// BeginTimeout bounds how long starting a transaction may take, so that
+// a saturated pool fails fast with ErrBeginTimeout instead of blocking
+// until the caller's deadline.
+//
+// For database/sql backends the timeout covers acquiring a connection
+// from the pool; for TimescaleDB it covers acquisition and BEGIN.
+// Zero, the default, disables the timeout.
+func BeginTimeout(d time.Duration) Option {
+	return func(r *BaseRepo) {
+		r.beginTimeout = d
+	}
+}
+
+// defaultRollbackTimeout bounds rollbacks unless changed with
+// RollbackTimeout.
+const defaultRollbackTimeout = 5 * time.Second
+
+// RollbackTimeout bounds how long a TimescaleDB rollback may take. The
+// rollback is sent on a context detached from the caller's, so that it
+// still reaches the server and releases locks after the caller's context
+// is done; d keeps it from hanging on an unresponsive server. It
+// defaults to 5 seconds. database/sql rolls back on its own when the
+// context of a transaction is done.
+func RollbackTimeout(d time.Duration) Option {
+	return func(r *BaseRepo) {
+		if d > 0 {
+			r.rollbackTimeout = d
+		}
+	}
+}
+
+// CommitGracePeriod lets a TimescaleDB commit that has started complete
+// within d even if the caller's context expires meanwhile, so that valid
+// work finished right at a request timeout is not lost. A transaction
+// whose context is already done when fn returns is still rolled back. By
+// default commits are bound by the caller's context. database/sql
+// commits are not interrupted by their context once started.
+func CommitGracePeriod(d time.Duration) Option {
+	return func(r *BaseRepo) {
+		r.commitGracePeriod = d
+	}
+}
+
+// RecoverPanics sets the policy for panics raised inside a transaction.
+//
+// By default the transaction is rolled back and the panic propagates to
+// the caller. When enabled, the panic is recovered instead: the
+// transaction is rolled back and a *PanicError, reading
+// "panic in transaction: <value>", is returned as the error.
+//
+// A panic raised once the transaction has committed or rolled back, by
+// an after-commit or after-rollback callback or a hook, always
+// propagates: the transaction's outcome is already settled.
+func RecoverPanics(enabled bool) Option {
+	return func(r *BaseRepo) {
+		r.recoverPanics = enabled
+	}
+}
+
+// WithDefaultPostgresTxOptions sets the options PostgreSQL transactions
+// are started with when none are given, such as by WithPostgresDBTx or
+// WithPostgresDBTxOpts with nil options.
+//
+// Options passed explicitly to a *Opts variant replace the default as a
+// whole. The default is not applied to reused transactions, so nested
+// calls never conflict with it.
+func WithDefaultPostgresTxOptions(opts *sql.TxOptions) Option {
+	return func(r *BaseRepo) {
+		if opts == nil {
+			r.postgresOpts = nil
+			return
+		}
+		defaults := *opts
+		r.postgresOpts = &defaults
+	}
+}
+
+// WithDefaultTimescaleTxOptions sets the options TimescaleDB
+// transactions are started with when none are given, such as by
+// WithTimescaleDBTx or WithTimescaleDBTxOpts with the zero value.
+//
+// Options passed explicitly to a *Opts variant replace the default as a
+// whole.
+func WithDefaultTimescaleTxOptions(opts pgx.TxOptions) Option {
+	return func(r *BaseRepo) {
+		r.timescaleOpts = opts
+	}
+}
+
+// WithDefaultPostgresRetry makes WithPostgresDBTx, WithPostgresDBTxOpts
+// and the helpers built on them retry new PostgreSQL transactions
+// according to cfg, as WithPostgresDBTxRetry does, so that callers need
+// not wrap every call. fn may then run more than once and must not have
+// side effects outside the transaction. Reused transactions are never
+// retried, nor are transactions started within a transaction of another
+// backend or repository, such as those of WithDualTx or
+// CompositeTxRepository: the outermost transaction is retried as a whole
+// instead. Nil, the default, disables retries.
+func WithDefaultPostgresRetry(cfg *RetryConfig) Option {
+	return func(r *BaseRepo) {
+		r.postgresRetry = copyRetryConfig(cfg)
+	}
+}
+
+// WithDefaultTimescaleRetry makes WithTimescaleDBTx, WithTimescaleDBTxOpts
+// and the helpers built on them retry new TimescaleDB transactions
+// according to cfg. See WithDefaultPostgresRetry.
+func WithDefaultTimescaleRetry(cfg *RetryConfig) Option {
+	return func(r *BaseRepo) {
+		r.timescaleRetry = copyRetryConfig(cfg)
+	}
+}
+
+// copyRetryConfig returns a copy of cfg, so that later changes by the
+// caller do not affect the repository.
+func copyRetryConfig(cfg *RetryConfig) *RetryConfig {
+	if cfg == nil {
+		return nil
+	}
+	c := *cfg
+	return &c
+}
+
+// WithIdempotencyTable sets the dedup table used by
+// WithPostgresDBTxIdempotent. The name is inserted into the SQL as is,
+// so it may be schema-qualified but must come from trusted configuration.
+func WithIdempotencyTable(table string) Option {
+	return func(r *BaseRepo) {
+		if table != "" {
+			r.idempotencyTable = table
+		}
+	}
+}
+
+// CacheStatements makes the executors returned inside a database/sql
+// transaction, such as by PostgresQueryExecutor, prepare each distinct
+// SQL text once per transaction and reuse the prepared statement, saving
+// parse and plan cycles on queries repeated within a transaction.
+// Statements are released when the transaction ends; queries made
+// outside a transaction are not prepared.
+//
+// For TimescaleDB, pgx caches prepared statements per connection on its
+// own, see ConfigureTimescaleStatementCache.
+func CacheStatements(enabled bool) Option {
+	return func(r *BaseRepo) {
+		r.cacheStatements = enabled
+	}
+}
+
+// LogFailedStatements records the SQL text of the last statement that
+// failed through the executor of a database/sql transaction, such as
+// PostgresQueryExecutor, and adds it as last_failed_query to the log
+// record of the transaction's rollback. Only the query text is kept,
+// never its arguments, but the text itself may still contain sensitive
+// literals. Statements run directly on the *sql.Tx, and TimescaleDB
+// statements, are not recorded. It is disabled by default.
+func LogFailedStatements(enabled bool) Option {
+	return func(r *BaseRepo) {
+		r.logFailedStmts = enabled
+	}
+}
+
+// RollbackOnCancel makes TimescaleDB transactions cancel the statement
+// in progress on the server as soon as their context is done, even one
+// sent with another context, so that fn fails promptly and its locks are
+// released when a client goes away. The cancellation is sent over a
+// separate connection, and the transaction itself is rolled back by the
+// helper once fn returns, so fn may keep using it meanwhile.
+// database/sql transactions are always rolled back by database/sql when
+// their context is done.
+func RollbackOnCancel(enabled bool) Option {
+	return func(r *BaseRepo) {
+		r.rollbackOnCancel = enabled
+	}
+}
+
+// SlowTxThreshold logs a warning, with the backend, label and duration,
+// for every transaction that stays open longer than d, reported when it
+// commits or rolls back. It only detects slow transactions and never
+// aborts them. Zero, the default, disables the warning.
+func SlowTxThreshold(d time.Duration) Option {
+	return func(r *BaseRepo) {
+		r.slowTxThreshold = d
+	}
+}
+
+// MaxTxLifetime aborts every transaction started by the repository that
+// is still open d after it was requested, so that a stuck transaction
+// cannot hold its locks and connection indefinitely.
+//
+// On expiry the context of the transaction is cancelled: database/sql
+// rolls the transaction back at once, and TimescaleDB transactions have
+// their statement in progress cancelled, as with RollbackOnCancel, and
+// are rolled back once fn returns. fn keeps running until it returns,
+// and the helper then reports an error wrapping ErrTxLifetimeExceeded
+// instead of committing. A commit already under way is not interrupted.
+// Zero, the default, disables the limit.
+func MaxTxLifetime(d time.Duration) Option {
+	return func(r *BaseRepo) {
+		r.maxTxLifetime = d
+	}
+}
+
+// LabelApplicationName sets application_name to the transaction's label,
+// as given to WithPostgresDBTxLabeled, for every labeled PostgreSQL and
+// TimescaleDB transaction, so that DBAs can tell from pg_stat_activity
+// which operation holds a transaction. The setting is made with SET LOCAL
+// and ends with the transaction. An ApplicationName passed per call takes
+// precedence.
+func LabelApplicationName(enabled bool) Option {
+	return func(r *BaseRepo) {
+		r.labelAppName = enabled
+	}
+}
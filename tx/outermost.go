@@ -0,0 +1,26 @@
+package tx
+
+import "context"
+
+// outermostKey stores whether the innermost transaction helper in the
+// context started its transaction or joined an existing one.
+const outermostKey contextKey = "tx_outermost"
+
+// IsOutermostTx reports whether the function receiving ctx was called by
+// the transaction helper that started the transaction.
+//
+// It is true inside the outermost WithPostgresDBTx (or any other helper
+// that begins a transaction) and false inside nested calls that reuse
+// the active transaction, including savepoints, so setup that must happen
+// once per unit of work can be guarded by it. It is false outside any
+// transaction.
+func IsOutermostTx(ctx context.Context) bool {
+	outermost, _ := ctx.Value(outermostKey).(bool)
+	return outermost
+}
+
+// withOutermost marks ctx as belonging to the helper that started the
+// transaction, or to a nested one that reuses it.
+func withOutermost(ctx context.Context, outermost bool) context.Context {
+	return context.WithValue(ctx, outermostKey, outermost)
+}
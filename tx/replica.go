@@ -0,0 +1,188 @@
+package tx
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"math/rand/v2"
+	"sync/atomic"
+	"time"
+)
+
+// readOnlyKey marks a context whose queries may be served by a replica.
+const readOnlyKey contextKey = "tx_read_only"
+
+// defaultReplicaCooldown is how long a failing replica is skipped unless
+// changed with WithReplicaCooldown.
+const defaultReplicaCooldown = 30 * time.Second
+
+// MarkReadOnly returns a child context whose PostgreSQL queries may be
+// routed to the read replicas configured with WithPostgresReplica or
+// WithPostgresReplicas.
+//
+// Only queries made outside a transaction are routed: inside one,
+// PostgresQueryExecutor always returns the primary's transaction.
+// Replicas can lag behind the primary, so mark only paths that tolerate
+// slightly stale reads.
+func MarkReadOnly(ctx context.Context) context.Context {
+	return context.WithValue(ctx, readOnlyKey, true)
+}
+
+// isReadOnly reports whether ctx was marked with MarkReadOnly.
+func isReadOnly(ctx context.Context) bool {
+	readOnly, _ := ctx.Value(readOnlyKey).(bool)
+	return readOnly
+}
+
+// -----------------------------
+// Replica Configuration
+// -----------------------------
+
+// Replica is a PostgreSQL read replica and its share of the read load.
+type Replica struct {
+	DB *sql.DB
+
+	// Weight is the relative share of queries sent to the replica.
+	// Values below one are treated as one.
+	Weight int
+}
+
+// WithPostgresReplica adds a read replica that serves PostgreSQL queries
+// made outside a transaction on contexts marked with MarkReadOnly. It is
+// equivalent to WithPostgresReplicas with a weight of one. Transactions
+// always run on the primary.
+func WithPostgresReplica(db *sql.DB) Option {
+	return WithPostgresReplicas(Replica{DB: db, Weight: 1})
+}
+
+// WithPostgresReplicas adds read replicas that serve PostgreSQL queries
+// made outside a transaction on contexts marked with MarkReadOnly.
+//
+// Each query picks a replica at random in proportion to its weight. A
+// replica whose query fails with a connection-level error, rather than
+// an error reported by the server, is skipped for the cool-down set with
+// WithReplicaCooldown. When every replica is cooling down, queries go to
+// the primary. Failures surfacing only from QueryRowContext's Scan are
+// not observed.
+func WithPostgresReplicas(replicas ...Replica) Option {
+	return func(r *BaseRepo) {
+		for _, rep := range replicas {
+			if rep.DB != nil {
+				r.replicas = append(r.replicas, &replica{db: rep.DB, weight: max(rep.Weight, 1)})
+			}
+		}
+	}
+}
+
+// WithReplicaCooldown sets how long a read replica is skipped after a
+// failed query. It defaults to 30 seconds.
+func WithReplicaCooldown(d time.Duration) Option {
+	return func(r *BaseRepo) {
+		r.replicaCooldown = d
+	}
+}
+
+// -----------------------------
+// Replica Routing
+// -----------------------------
+
+// replica is a configured read replica and the state of its circuit
+// breaker.
+type replica struct {
+	db     *sql.DB
+	weight int
+
+	// failedAt is the time of the last failure in Unix nanoseconds, or
+	// zero if the replica is healthy.
+	failedAt atomic.Int64
+}
+
+// healthy reports whether the replica is outside its cool-down at now.
+func (rep *replica) healthy(now time.Time, cooldown time.Duration) bool {
+	failedAt := rep.failedAt.Load()
+	return failedAt == 0 || now.Sub(time.Unix(0, failedAt)) >= cooldown
+}
+
+// observe trips the breaker when err indicates the replica itself is
+// unavailable, and resets it after a successful query.
+func (rep *replica) observe(ctx context.Context, clock Clock, err error) {
+	switch {
+	case err == nil:
+		rep.failedAt.Store(0)
+	case ctx.Err() != nil, errors.Is(err, sql.ErrNoRows), sqlState(err) != "":
+		// The caller gave up, or the server answered: not a replica fault.
+	default:
+		rep.failedAt.Store(clock.Now().UnixNano())
+	}
+}
+
+// postgresReader returns the executor that serves queries made outside
+// a transaction on ctx.
+func (r *BaseRepo) postgresReader(ctx context.Context) SQLExecutor {
+	if len(r.replicas) == 0 || !isReadOnly(ctx) {
+		return r.postgresPrimary()
+	}
+	if rep := r.pickReplica(); rep != nil {
+		return replicaExecutor{rep: rep, clock: r.clock}
+	}
+	return r.postgresPrimary()
+}
+
+// postgresPrimary returns the executor for statements made on the
+// PostgreSQL primary outside a transaction.
+func (r *BaseRepo) postgresPrimary() SQLExecutor {
+	if r.postgresDriver != nil {
+		return r.postgresDriver
+	}
+	return r.postgresDB
+}
+
+// pickReplica chooses a healthy replica at random in proportion to its
+// weight, or returns nil if all are cooling down.
+func (r *BaseRepo) pickReplica() *replica {
+	now := r.clock.Now()
+
+	healthy := make([]*replica, 0, len(r.replicas))
+	total := 0
+	for _, rep := range r.replicas {
+		if rep.healthy(now, r.replicaCooldown) {
+			healthy = append(healthy, rep)
+			total += rep.weight
+		}
+	}
+	if total == 0 {
+		return nil
+	}
+
+	n := rand.N(total)
+	for _, rep := range healthy {
+		if n < rep.weight {
+			return rep
+		}
+		n -= rep.weight
+	}
+	return healthy[len(healthy)-1]
+}
+
+// replicaExecutor runs queries on a replica and feeds their outcome to
+// its circuit breaker.
+type replicaExecutor struct {
+	rep   *replica
+	clock Clock
+}
+
+func (e replicaExecutor) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	res, err := e.rep.db.ExecContext(ctx, query, args...)
+	e.rep.observe(ctx, e.clock, err)
+	return res, err
+}
+
+func (e replicaExecutor) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	rows, err := e.rep.db.QueryContext(ctx, query, args...)
+	e.rep.observe(ctx, e.clock, err)
+	return rows, err
+}
+
+func (e replicaExecutor) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	return e.rep.db.QueryRowContext(ctx, query, args...)
+}
@@ -0,0 +1,112 @@
+package tx_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/arunni/go-db-tx/tx"
+	"github.com/arunni/go-db-tx/tx/txtest"
+)
+
+// manualClock is a Clock whose time only moves when advanced.
+type manualClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *manualClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *manualClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+
+func (c *manualClock) advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+func TestReplicaRouting(t *testing.T) {
+	primary, replica := txtest.NewSpy(), txtest.NewSpy()
+	r := tx.NewBaseRepo(primary.DB(), nil, tx.WithPostgresReplica(replica.DB()))
+	ctx := context.Background()
+
+	if _, err := r.PostgresQueryExecutor(tx.MarkReadOnly(ctx)).ExecContext(ctx, "SELECT replica"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.PostgresQueryExecutor(ctx).ExecContext(ctx, "SELECT unmarked"); err != nil {
+		t.Fatal(err)
+	}
+	err := r.WithPostgresDBTx(tx.MarkReadOnly(ctx), func(ctx context.Context) error {
+		_, err := r.PostgresQueryExecutor(ctx).ExecContext(ctx, "SELECT in tx")
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assertQueries(t, replica, "SELECT replica")
+	assertQueries(t, primary, "SELECT unmarked", "SELECT in tx")
+}
+
+func TestReplicasShareTheLoad(t *testing.T) {
+	primary, a, b := txtest.NewSpy(), txtest.NewSpy(), txtest.NewSpy()
+	r := tx.NewBaseRepo(primary.DB(), nil, tx.WithPostgresReplicas(
+		tx.Replica{DB: a.DB(), Weight: 1},
+		tx.Replica{DB: b.DB(), Weight: 1},
+	))
+	ctx := tx.MarkReadOnly(context.Background())
+
+	for range 100 {
+		if _, err := r.PostgresQueryExecutor(ctx).ExecContext(ctx, "SELECT 1"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := len(a.Queries()) + len(b.Queries()); got != 100 || len(a.Queries()) == 0 || len(b.Queries()) == 0 {
+		t.Fatalf("replicas served %d and %d queries, want 100 shared by both", len(a.Queries()), len(b.Queries()))
+	}
+	if got := primary.Queries(); len(got) != 0 {
+		t.Fatalf("primary served %v, want nothing", got)
+	}
+}
+
+func TestReplicaCircuitBreaker(t *testing.T) {
+	primary, replica := txtest.NewSpy(), txtest.NewSpy()
+	clock := &manualClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	r := tx.NewBaseRepo(primary.DB(), nil,
+		tx.WithPostgresReplica(replica.DB()),
+		tx.WithReplicaCooldown(time.Minute),
+		tx.WithClock(clock),
+	)
+	ctx := tx.MarkReadOnly(context.Background())
+	exec := func(query string) {
+		t.Helper()
+		_, _ = r.PostgresQueryExecutor(ctx).ExecContext(ctx, query)
+	}
+
+	replica.StubError("SELECT down", errors.New("connection refused"))
+	exec("SELECT down")
+	exec("SELECT cooling down")
+	clock.advance(time.Minute)
+	exec("SELECT recovered")
+
+	assertQueries(t, replica, "SELECT down", "SELECT recovered")
+	assertQueries(t, primary, "SELECT cooling down")
+}
+
+func TestReplicaServerErrorKeepsBreakerClosed(t *testing.T) {
+	primary, replica := txtest.NewSpy(), txtest.NewSpy()
+	r := tx.NewBaseRepo(primary.DB(), nil, tx.WithPostgresReplica(replica.DB()))
+	ctx := tx.MarkReadOnly(context.Background())
+
+	replica.StubError("SELECT bad", errSerialization)
+	_, _ = r.PostgresQueryExecutor(ctx).ExecContext(ctx, "SELECT bad")
+	_, _ = r.PostgresQueryExecutor(ctx).ExecContext(ctx, "SELECT next")
+
+	assertQueries(t, replica, "SELECT bad", "SELECT next")
+}
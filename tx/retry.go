@@ -0,0 +1,293 @@
+package tx
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"log/slog"
+	"math"
+	"math/rand/v2"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// SQLSTATE codes for errors that are resolved by retrying the whole
+// transaction.
+const (
+	sqlStateSerializationFailure = "40001"
+	sqlStateDeadlockDetected     = "40P01"
+)
+
+// sqlStateQueryCanceled is reported when a statement is cancelled, for
+// example by statement_timeout.
+const sqlStateQueryCanceled = "57014"
+
+// sqlStateInFailedTransaction is reported for statements issued in a
+// transaction that an earlier failed statement has aborted.
+const sqlStateInFailedTransaction = "25P02"
+
+// sqlStateLockNotAvailable is reported when a lock cannot be acquired,
+// for example because lock_timeout was exceeded.
+const sqlStateLockNotAvailable = "55P03"
+
+// sqlStateIdleInTransactionTimeout is reported when the server ends a
+// session whose transaction stayed idle longer than
+// idle_in_transaction_session_timeout.
+const sqlStateIdleInTransactionTimeout = "25P03"
+
+// sqlStateError is implemented by driver errors that expose a SQLSTATE,
+// such as *pgconn.PgError (pgx and its database/sql stdlib driver) and
+// *pq.Error (lib/pq).
+type sqlStateError interface {
+	SQLState() string
+}
+
+// sqlState returns the SQLSTATE carried by err, or an empty string if
+// err does not originate from the database server.
+func sqlState(err error) string {
+	var stateErr sqlStateError
+	if errors.As(err, &stateErr) {
+		return stateErr.SQLState()
+	}
+	return ""
+}
+
+// IsSerializationFailure reports whether err is a serialization failure
+// (SQLSTATE 40001) reported by the server through pgx, its database/sql
+// driver or any driver error exposing SQLState.
+func IsSerializationFailure(err error) bool {
+	return sqlState(err) == sqlStateSerializationFailure
+}
+
+// IsDeadlock reports whether err is a deadlock detected by the server
+// (SQLSTATE 40P01).
+func IsDeadlock(err error) bool {
+	return sqlState(err) == sqlStateDeadlockDetected
+}
+
+// IsRetryable reports whether err is a serialization failure or a
+// detected deadlock, the errors retried by default.
+func IsRetryable(err error) bool {
+	return IsSerializationFailure(err) || IsDeadlock(err)
+}
+
+// IsConnectionError reports whether err means the connection to the
+// database was lost or could not be established, as opposed to an error
+// reported by the server about a statement.
+//
+// It recognizes pgconn connection failures, driver.ErrBadConn, an
+// unexpected end of stream, network errors, and the SQLSTATEs of class 08
+// (connection exception) and of a server shutting down (57P01 to 57P03).
+// Errors caused by a cancelled context are not connection errors.
+func IsConnectionError(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	if state := sqlState(err); state != "" {
+		return strings.HasPrefix(state, "08") || state == "57P01" || state == "57P02" || state == "57P03"
+	}
+
+	var connectErr *pgconn.ConnectError
+	var netErr net.Error
+	return errors.As(err, &connectErr) ||
+		errors.As(err, &netErr) ||
+		errors.Is(err, driver.ErrBadConn) ||
+		errors.Is(err, io.EOF) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, net.ErrClosed)
+}
+
+// -----------------------------
+// Retry Configuration
+// -----------------------------
+
+// RetryConfig controls how failed transactions are retried.
+//
+// The delay before retry n (starting at 1) is BaseDelay multiplied by
+// Multiplier^(n-1), capped at MaxDelay. With Jitter enabled the delay is
+// randomized between half and the full computed value so that competing
+// callers spread out instead of retrying in lockstep.
+type RetryConfig struct {
+	// MaxAttempts bounds the total number of attempts, including the
+	// first one. Values below one are treated as one.
+	MaxAttempts int
+
+	// BaseDelay is the delay before the first retry. Zero retries
+	// immediately.
+	BaseDelay time.Duration
+
+	// MaxDelay caps the delay between attempts. Zero means no cap.
+	MaxDelay time.Duration
+
+	// Multiplier is the growth factor applied to the delay after each
+	// attempt. Values below one are treated as one (constant delay).
+	Multiplier float64
+
+	// Jitter randomizes each delay to avoid thundering-herd retries.
+	Jitter bool
+
+	// RetryLockTimeouts also retries transactions that failed because a
+	// lock could not be acquired in time (SQLSTATE 55P03), see
+	// LockTimeout.
+	RetryLockTimeouts bool
+
+	// IsRetryable decides which errors are retried. Nil selects the
+	// package-level IsRetryable: serialization failures and deadlocks.
+	IsRetryable func(err error) bool
+}
+
+// attempts returns the effective number of attempts.
+func (c RetryConfig) attempts() int {
+	if c.MaxAttempts < 1 {
+		return 1
+	}
+	return c.MaxAttempts
+}
+
+// retryable reports whether a transaction that failed with err should
+// be retried.
+func (c RetryConfig) retryable(err error) bool {
+	isRetryable := c.IsRetryable
+	if isRetryable == nil {
+		isRetryable = IsRetryable
+	}
+	return isRetryable(err) || (c.RetryLockTimeouts && IsLockTimeout(err))
+}
+
+// backoff returns the delay to wait after the given failed attempt.
+func (c RetryConfig) backoff(attempt int) time.Duration {
+	if c.BaseDelay <= 0 {
+		return 0
+	}
+
+	multiplier := math.Max(c.Multiplier, 1)
+	delay := float64(c.BaseDelay) * math.Pow(multiplier, float64(attempt-1))
+	if c.MaxDelay > 0 && delay > float64(c.MaxDelay) {
+		delay = float64(c.MaxDelay)
+	}
+
+	// Without MaxDelay, a large attempt overflows int64.
+	d := time.Duration(math.MaxInt64)
+	if delay < math.MaxInt64 {
+		d = time.Duration(delay)
+	}
+	if c.Jitter && d > 1 {
+		d = d/2 + rand.N(d/2)
+	}
+	return d
+}
+
+// sleep waits for d on clock or until ctx is done, whichever happens
+// first.
+func sleep(ctx context.Context, clock Clock, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-clock.After(d):
+		return nil
+	}
+}
+
+// -----------------------------
+// PostgreSQL Retry
+// -----------------------------
+
+// WithPostgresDBTxRetry executes the given function within a PostgreSQL
+// transaction, re-running it in a fresh transaction when it fails with a
+// serialization failure (40001) or a detected deadlock (40P01), and
+// optionally a lock timeout (55P03), or with the errors selected by
+// cfg.IsRetryable.
+//
+// Attempts are bounded and spaced out according to cfg. Any other error
+// is returned immediately. If ctx is cancelled while waiting between
+// attempts, ctx.Err() is returned. When all attempts fail, the last error
+// is returned wrapped in a *RetryExhaustedError and hooks implementing
+// RetryHook are notified.
+//
+// txOpts configure each attempt's transaction, so that for example
+// LockTimeout combined with RetryLockTimeouts retries transactions that
+// give up waiting for a lock.
+//
+// If a transaction already exists in the context, fn is run once within
+// it: only the caller that started a transaction can retry it.
+func (r *BaseRepo) WithPostgresDBTxRetry(
+	ctx context.Context,
+	cfg RetryConfig,
+	fn func(ctx context.Context) error,
+	txOpts ...TxOption,
+) error {
+
+	// A reused transaction cannot be restarted from here
+	if r.IsInPostgresTx(ctx) {
+		return fn(withOutermost(ctx, false))
+	}
+
+	return r.retry(ctx, BackendPostgres, cfg, func(ctx context.Context) error {
+		return r.withSQLTx(ctx, r.postgres(), nil, newTxConfig(txOpts), fn)
+	})
+}
+
+// outsideTx reports whether no transaction, of any backend or
+// repository, is open in ctx. Default retry policies only apply then:
+// re-running fn within an enclosing transaction would not undo what the
+// failed attempts did in it.
+func outsideTx(ctx context.Context) bool {
+	_, ok := scopeFromContext(ctx)
+	return !ok
+}
+
+// retry runs attempt, each time with its attempt number in the context,
+// until it succeeds, fails with an error cfg does not retry, or cfg's
+// attempts are exhausted.
+func (r *BaseRepo) retry(
+	ctx context.Context,
+	backend Backend,
+	cfg RetryConfig,
+	attempt func(ctx context.Context) error,
+) error {
+
+	maxAttempts := cfg.attempts()
+
+	var err error
+	for n := 1; n <= maxAttempts; n++ {
+		err = attempt(withAttempt(ctx, n))
+		if err == nil || !cfg.retryable(err) {
+			return err
+		}
+		if n == maxAttempts {
+			break
+		}
+		if err := sleep(ctx, r.clock, cfg.backoff(n)); err != nil {
+			return err
+		}
+		r.metrics.IncRetried(backend)
+	}
+
+	r.reportExhausted(ctx, backend, maxAttempts, err)
+	return &RetryExhaustedError{Attempts: maxAttempts, Err: err}
+}
+
+// reportExhausted logs that a transaction on backend failed after
+// attempts attempts, the last one with err, and notifies the RetryHook
+// hooks.
+func (r *BaseRepo) reportExhausted(ctx context.Context, backend Backend, attempts int, err error) {
+	r.logger.LogAttrs(ctx, slog.LevelError, "transaction retries exhausted",
+		slog.String("backend", string(backend)),
+		slog.Int("attempts", attempts),
+		slog.Any("error", err),
+	)
+	for _, h := range r.hooks {
+		if rh, ok := h.(RetryHook); ok {
+			rh.OnRetryExhausted(ctx, backend, attempts, err)
+		}
+	}
+}
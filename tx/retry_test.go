@@ -0,0 +1,183 @@
+package tx_test
+
+import (
+	"context"
+	"errors"
+	"math"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/arunni/go-db-tx/tx"
+	"github.com/arunni/go-db-tx/tx/txtest"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// sleepClock is a Clock whose After fires at once and records the
+// requested delays.
+type sleepClock struct {
+	mu     sync.Mutex
+	delays []time.Duration
+}
+
+func (c *sleepClock) Now() time.Time { return time.Unix(0, 0) }
+
+func (c *sleepClock) After(d time.Duration) <-chan time.Time {
+	c.mu.Lock()
+	c.delays = append(c.delays, d)
+	c.mu.Unlock()
+
+	ch := make(chan time.Time, 1)
+	ch <- c.Now()
+	return ch
+}
+
+func (c *sleepClock) recorded() []time.Duration {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]time.Duration(nil), c.delays...)
+}
+
+var errSerialization = &pgconn.PgError{Code: "40001"}
+
+func TestRetryBackoff(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  tx.RetryConfig
+		want []time.Duration
+	}{
+		{
+			name: "constant",
+			cfg:  tx.RetryConfig{MaxAttempts: 4, BaseDelay: 10 * time.Millisecond},
+			want: []time.Duration{10 * time.Millisecond, 10 * time.Millisecond, 10 * time.Millisecond},
+		},
+		{
+			name: "exponential with cap",
+			cfg:  tx.RetryConfig{MaxAttempts: 5, BaseDelay: 10 * time.Millisecond, Multiplier: 2, MaxDelay: 50 * time.Millisecond},
+			want: []time.Duration{10 * time.Millisecond, 20 * time.Millisecond, 40 * time.Millisecond, 50 * time.Millisecond},
+		},
+		{
+			name: "overflow without cap",
+			cfg:  tx.RetryConfig{MaxAttempts: 4, BaseDelay: time.Hour, Multiplier: 1e6},
+			want: []time.Duration{time.Hour, time.Hour * 1e6, math.MaxInt64},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clock := &sleepClock{}
+			r := tx.NewBaseRepo(txtest.NewSpy().DB(), nil, tx.WithClock(clock))
+
+			attempts := 0
+			err := r.WithPostgresDBTxRetry(context.Background(), tt.cfg, func(ctx context.Context) error {
+				attempts++
+				return errSerialization
+			})
+
+			var exhausted *tx.RetryExhaustedError
+			if !errors.As(err, &exhausted) || exhausted.Attempts != tt.cfg.MaxAttempts {
+				t.Fatalf("err = %v, want RetryExhaustedError after %d attempts", err, tt.cfg.MaxAttempts)
+			}
+			if attempts != tt.cfg.MaxAttempts {
+				t.Fatalf("attempts = %d, want %d", attempts, tt.cfg.MaxAttempts)
+			}
+			if got := clock.recorded(); !slices.Equal(got, tt.want) {
+				t.Fatalf("delays = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRetryJitterStaysPositive(t *testing.T) {
+	clock := &sleepClock{}
+	r := tx.NewBaseRepo(txtest.NewSpy().DB(), nil, tx.WithClock(clock))
+
+	cfg := tx.RetryConfig{MaxAttempts: 6, BaseDelay: time.Hour, Multiplier: 1e9, Jitter: true}
+	_ = r.WithPostgresDBTxRetry(context.Background(), cfg, func(ctx context.Context) error {
+		return errSerialization
+	})
+
+	for _, d := range clock.recorded() {
+		if d <= 0 {
+			t.Fatalf("delays = %v, want all positive", clock.recorded())
+		}
+	}
+}
+
+func TestRetryNonRetryableError(t *testing.T) {
+	spy := txtest.NewSpy()
+	r := tx.NewBaseRepo(spy.DB(), nil, tx.WithClock(&sleepClock{}))
+
+	boom := errors.New("boom")
+	attempts := 0
+	err := r.WithPostgresDBTxRetry(context.Background(), tx.RetryConfig{MaxAttempts: 3}, func(ctx context.Context) error {
+		attempts++
+		return boom
+	})
+	if !errors.Is(err, boom) || attempts != 1 {
+		t.Fatalf("err = %v after %d attempts, want boom after 1", err, attempts)
+	}
+}
+
+func TestRetrySucceedsInFreshTransaction(t *testing.T) {
+	spy := txtest.NewSpy()
+	r := tx.NewBaseRepo(spy.DB(), nil, tx.WithClock(&sleepClock{}))
+
+	attempts := 0
+	err := r.WithPostgresDBTxRetry(context.Background(), tx.RetryConfig{MaxAttempts: 3}, func(ctx context.Context) error {
+		attempts++
+		if attempts < 2 {
+			return errSerialization
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assertKinds(t, spy, txtest.CallBegin, txtest.CallRollback, txtest.CallBegin, txtest.CallCommit)
+}
+
+func TestDefaultRetryWithinAnotherTransaction(t *testing.T) {
+	retry := tx.WithDefaultPostgresRetry(&tx.RetryConfig{MaxAttempts: 3})
+	tests := []struct {
+		name         string
+		outerRetries bool
+		want         int
+	}{
+		{"inner transaction is not retried", false, 1},
+		{"outermost transaction is retried as a whole", true, 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			outerOpts := []tx.Option{tx.WithNamespace("outer"), tx.WithClock(&sleepClock{})}
+			if tt.outerRetries {
+				outerOpts = append(outerOpts, retry)
+			}
+			outer := tx.NewBaseRepo(txtest.NewSpy().DB(), nil, outerOpts...)
+			innerSpy := txtest.NewSpy()
+			inner := tx.NewBaseRepo(innerSpy.DB(), nil, tx.WithNamespace("inner"), tx.WithClock(&sleepClock{}), retry)
+
+			attempts := 0
+			_ = tx.NewCompositeTxRepository(outer, inner).WithPostgresDBTx(context.Background(), func(ctx context.Context) error {
+				attempts++
+				return errSerialization
+			})
+			if attempts != tt.want {
+				t.Fatalf("attempts = %d, want %d", attempts, tt.want)
+			}
+
+			var begins int
+			for _, c := range innerSpy.Calls() {
+				if c.Kind == txtest.CallBegin {
+					begins++
+				}
+			}
+			if begins != tt.want {
+				t.Fatalf("inner transactions = %d, want %d", begins, tt.want)
+			}
+		})
+	}
+}
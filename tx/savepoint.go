@@ -0,0 +1,201 @@
+package tx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync/atomic"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// savepointSeqKey stores, in the transaction store, the counter that
+// numbers the savepoints of a transaction.
+type savepointSeqKey struct{}
+
+// SavepointOption configures a savepoint created by
+// WithPostgresDBSavepoint or WithPostgresDBSavepointIf.
+type SavepointOption func(*savepointConfig)
+
+// savepointConfig holds the settings of a single savepoint.
+type savepointConfig struct {
+	name string
+}
+
+// SavepointName names the savepoint, for example after the call site, so
+// that it is recognizable in server logs and errors. name is inserted
+// into the SQL as is and must be a valid SQL identifier. By default
+// savepoints are numbered per transaction: sp_1, sp_2, and so on.
+func SavepointName(name string) SavepointOption {
+	return func(c *savepointConfig) {
+		c.name = name
+	}
+}
+
+// savepointName returns the name of a new savepoint in the transaction
+// of ctx: the one chosen with SavepointName, or the next number of the
+// transaction's counter.
+func savepointName(ctx context.Context, opts []SavepointOption) string {
+	var cfg savepointConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	if cfg.name != "" {
+		return cfg.name
+	}
+
+	var n uint64
+	if store, ok := TxStore(ctx); ok {
+		seq, _ := store.LoadOrStore(savepointSeqKey{}, new(atomic.Uint64))
+		n = seq.(*atomic.Uint64).Add(1)
+	}
+	return fmt.Sprintf("sp_%d", n)
+}
+
+// -----------------------------
+// PostgreSQL Savepoint
+// -----------------------------
+
+// WithPostgresDBSavepoint executes the given function within a savepoint
+// of the PostgreSQL transaction found in the context.
+//
+// The savepoint is released when fn succeeds and rolled back to when fn
+// returns an error or panics, so a failed sub-operation does not poison
+// the surrounding transaction. fn's error is returned unchanged and the
+// outer transaction remains usable.
+//
+// If no transaction exists in the context, this behaves like
+// WithPostgresDBTx and opts are ignored.
+func (r *BaseRepo) WithPostgresDBSavepoint(
+	ctx context.Context,
+	fn func(ctx context.Context) error,
+	opts ...SavepointOption,
+) error {
+	return r.WithPostgresDBSavepointIf(ctx, func(error) bool { return true }, fn, opts...)
+}
+
+// WithPostgresDBSavepointIf is like WithPostgresDBSavepoint but lets
+// shouldRollback decide, per error returned by fn, whether to roll back
+// to the savepoint.
+//
+// When shouldRollback reports true, the savepoint is rolled back to and
+// the outer transaction remains usable, so the caller can handle the
+// error and continue, as in "insert, and on a unique violation fall
+// back to an update". Otherwise the savepoint is released, keeping what
+// fn did, and the error is returned for the outer transaction to fail
+// on. In both cases fn's error is returned unchanged; a panic always
+// rolls back to the savepoint.
+func (r *BaseRepo) WithPostgresDBSavepointIf(
+	ctx context.Context,
+	shouldRollback func(err error) bool,
+	fn func(ctx context.Context) error,
+	opts ...SavepointOption,
+) error {
+
+	tx, ok := r.postgresTx(ctx)
+	if !ok {
+		return r.WithPostgresDBTx(ctx, fn)
+	}
+
+	name := savepointName(ctx, opts)
+	if _, err := tx.ExecContext(ctx, "SAVEPOINT "+name); err != nil {
+		return err
+	}
+
+	rollback := func() error {
+		_, err := tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT "+name)
+		return err
+	}
+	release := func() error {
+		_, err := tx.ExecContext(ctx, "RELEASE SAVEPOINT "+name)
+		return err
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			if err := rollback(); err != nil {
+				r.logger.ErrorContext(ctx, "savepoint rollback failed",
+					slog.String("backend", string(BackendPostgres)),
+					slog.Any("error", err),
+				)
+			}
+			panic(p)
+		}
+	}()
+
+	if err := fn(withOutermost(ctx, false)); err != nil {
+		if shouldRollback(err) {
+			return errors.Join(err, rollback())
+		}
+		// A failed statement has aborted the transaction, which then
+		// rejects RELEASE too: that is reported by the outer
+		// transaction, so only fn's error is returned.
+		_ = release()
+		return err
+	}
+
+	return release()
+}
+
+// -----------------------------
+// TimescaleDB Savepoint
+// -----------------------------
+
+// WithTimescaleDBSavepoint executes the given function within a nested
+// transaction of the TimescaleDB transaction found in the context.
+//
+// The nested transaction is created with pgx.Tx.Begin, which is backed by
+// a savepoint. It shadows the outer transaction in the context passed to
+// fn, so TimescaleQueryExecutor and nested calls use it. It is committed
+// (released) when fn succeeds and rolled back when fn returns an error or
+// panics, leaving the outer transaction usable.
+//
+// pgx names the savepoints itself, numbering them per transaction.
+//
+// If no transaction exists in the context, this behaves like
+// WithTimescaleDBTx.
+func (r *BaseRepo) WithTimescaleDBSavepoint(
+	ctx context.Context,
+	fn func(ctx context.Context) error,
+) error {
+
+	active, ok := r.timescaleTxFromContext(ctx)
+	if !ok {
+		return r.WithTimescaleDBTx(ctx, fn)
+	}
+
+	nested, err := active.tx.Begin(ctx)
+	if err != nil {
+		return err
+	}
+
+	nestedCtx := context.WithValue(ctx, r.keys.timescale, &timescaleTx{tx: nested, opts: active.opts, scope: active.scope})
+	nestedCtx = withOutermost(nestedCtx, false)
+
+	defer func() {
+		if p := recover(); p != nil {
+			if err := r.rollbackNested(ctx, nested); err != nil {
+				r.logger.ErrorContext(ctx, "savepoint rollback failed",
+					slog.String("backend", string(BackendTimescale)),
+					slog.Any("error", err),
+				)
+			}
+			panic(p)
+		}
+	}()
+
+	if err := fn(nestedCtx); err != nil {
+		return errors.Join(err, r.rollbackNested(ctx, nested))
+	}
+
+	return nested.Commit(ctx)
+}
+
+// rollbackNested rolls back a nested TimescaleDB transaction with a
+// context that survives the cancellation of ctx.
+func (r *BaseRepo) rollbackNested(ctx context.Context, nested pgx.Tx) error {
+	rbCtx, cancel := r.rollbackContext(ctx)
+	defer cancel()
+	return rollbackErr(nested.Rollback(rbCtx))
+}
@@ -0,0 +1,79 @@
+package tx_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/arunni/go-db-tx/tx"
+	"github.com/arunni/go-db-tx/tx/txtest"
+)
+
+func TestSavepointSQL(t *testing.T) {
+	spy := txtest.NewSpy()
+	r := tx.NewBaseRepo(spy.DB(), nil)
+	boom := errors.New("boom")
+
+	err := r.WithPostgresDBTx(context.Background(), func(ctx context.Context) error {
+		if err := r.WithPostgresDBSavepoint(ctx, func(ctx context.Context) error { return nil }); err != nil {
+			return err
+		}
+		err := r.WithPostgresDBSavepoint(ctx, func(ctx context.Context) error { return boom }, tx.SavepointName("upsert"))
+		if !errors.Is(err, boom) {
+			t.Errorf("savepoint err = %v, want boom", err)
+		}
+		return r.WithPostgresDBSavepoint(ctx, func(ctx context.Context) error { return nil })
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assertQueries(t, spy,
+		"SAVEPOINT sp_1", "RELEASE SAVEPOINT sp_1",
+		"SAVEPOINT upsert", "ROLLBACK TO SAVEPOINT upsert",
+		"SAVEPOINT sp_2", "RELEASE SAVEPOINT sp_2",
+	)
+	assertKinds(t, spy,
+		txtest.CallBegin,
+		txtest.CallExec, txtest.CallExec,
+		txtest.CallExec, txtest.CallExec,
+		txtest.CallExec, txtest.CallExec,
+		txtest.CallCommit,
+	)
+}
+
+func TestSavepointIfKeepsWork(t *testing.T) {
+	spy := txtest.NewSpy()
+	r := tx.NewBaseRepo(spy.DB(), nil)
+	boom := errors.New("boom")
+
+	err := r.WithPostgresDBTx(context.Background(), func(ctx context.Context) error {
+		return r.WithPostgresDBSavepointIf(ctx, func(error) bool { return false }, func(ctx context.Context) error {
+			return boom
+		})
+	})
+	if !errors.Is(err, boom) {
+		t.Fatalf("err = %v, want boom", err)
+	}
+
+	assertQueries(t, spy, "SAVEPOINT sp_1", "RELEASE SAVEPOINT sp_1")
+	assertKinds(t, spy, txtest.CallBegin, txtest.CallExec, txtest.CallExec, txtest.CallRollback)
+}
+
+func TestSavepointWithoutTransaction(t *testing.T) {
+	spy := txtest.NewSpy()
+	r := tx.NewBaseRepo(spy.DB(), nil)
+
+	err := r.WithPostgresDBSavepoint(context.Background(), func(ctx context.Context) error {
+		if !r.IsInPostgresTx(ctx) {
+			t.Error("fn runs outside a transaction")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assertQueries(t, spy)
+	assertKinds(t, spy, txtest.CallBegin, txtest.CallCommit)
+}
@@ -0,0 +1,124 @@
+package tx
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// scopeKey stores the *txScope of the innermost root transaction.
+const scopeKey contextKey = "tx_scope"
+
+// txScope holds state shared by everything that runs within one root
+// transaction, however deeply calls are nested. Reused transactions and
+// savepoints share the scope of the transaction they belong to.
+type txScope struct {
+	// store and startedAt are shared with the scopes of transactions
+	// nested within this one, see TxStore and TxStartedAt.
+	store     *sync.Map
+	startedAt time.Time
+
+	// clock is the clock of the repository that started the outermost
+	// transaction, used by TxElapsed.
+	clock Clock
+
+	// rowsAffected is the total reported by statements run through the
+	// transaction's executors.
+	rowsAffected atomic.Int64
+
+	mu            sync.Mutex
+	rollbackOnly  bool
+	beforeCommit  []func(ctx context.Context) error
+	afterCommit   []func()
+	afterRollback []func()
+}
+
+// newScopeContext returns a child context carrying a fresh scope started
+// at clock's current time. The scope shares the store, start time and
+// clock of the enclosing scope, if any.
+func newScopeContext(ctx context.Context, clock Clock) (context.Context, *txScope) {
+	scope := &txScope{store: &sync.Map{}, startedAt: clock.Now(), clock: clock}
+	if parent, ok := scopeFromContext(ctx); ok {
+		scope.store = parent.store
+		scope.startedAt = parent.startedAt
+		scope.clock = parent.clock
+	}
+	return context.WithValue(ctx, scopeKey, scope), scope
+}
+
+// scopeFromContext returns the scope of the innermost root transaction.
+func scopeFromContext(ctx context.Context) (*txScope, bool) {
+	scope, ok := ctx.Value(scopeKey).(*txScope)
+	return scope, ok && scope != nil
+}
+
+// isRollbackOnly reports whether SetRollbackOnly was called within the
+// scope.
+func (s *txScope) isRollbackOnly() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.rollbackOnly
+}
+
+// runBeforeCommit runs the deferred functions in registration order
+// within the transaction, stopping at the first error. Functions deferred
+// by a deferred function run after it.
+func (s *txScope) runBeforeCommit(ctx context.Context) error {
+	for {
+		s.mu.Lock()
+		if len(s.beforeCommit) == 0 {
+			s.mu.Unlock()
+			return nil
+		}
+		fn := s.beforeCommit[0]
+		s.beforeCommit = s.beforeCommit[1:]
+		s.mu.Unlock()
+
+		if err := fn(ctx); err != nil {
+			return err
+		}
+	}
+}
+
+// committed runs the after-commit callbacks in registration order and
+// discards the after-rollback ones.
+func (s *txScope) committed() {
+	s.mu.Lock()
+	callbacks := s.afterCommit
+	s.beforeCommit, s.afterCommit, s.afterRollback = nil, nil, nil
+	s.mu.Unlock()
+
+	for _, cb := range callbacks {
+		cb()
+	}
+}
+
+// rolledBack runs the after-rollback callbacks in registration order and
+// discards the after-commit ones.
+func (s *txScope) rolledBack() {
+	s.mu.Lock()
+	callbacks := s.afterRollback
+	s.beforeCommit, s.afterCommit, s.afterRollback = nil, nil, nil
+	s.mu.Unlock()
+
+	for _, cb := range callbacks {
+		cb()
+	}
+}
+
+// adopt moves the callbacks and the rows affected of child, the scope of
+// a part of the transaction that completed, into s, so that they are
+// settled with the transaction as a whole.
+func (s *txScope) adopt(child *txScope) {
+	child.mu.Lock()
+	afterCommit, afterRollback := child.afterCommit, child.afterRollback
+	child.beforeCommit, child.afterCommit, child.afterRollback = nil, nil, nil
+	child.mu.Unlock()
+
+	s.mu.Lock()
+	s.afterCommit = append(s.afterCommit, afterCommit...)
+	s.afterRollback = append(s.afterRollback, afterRollback...)
+	s.mu.Unlock()
+	s.rowsAffected.Add(child.rowsAffected.Load())
+}
@@ -0,0 +1,38 @@
+package tx
+
+import "context"
+
+// ExecFunc runs a statement within the transaction being set up.
+type ExecFunc func(ctx context.Context, query string, args ...any) error
+
+// SetupFunc prepares a freshly begun transaction before the transaction
+// function runs, see OnTxBegin.
+type SetupFunc func(ctx context.Context, backend Backend, exec ExecFunc) error
+
+// OnTxBegin registers fn to run against every transaction the repository
+// starts, right after BEGIN and before the transaction function.
+//
+// fn runs inside the transaction, so settings it makes with SET LOCAL,
+// such as SET LOCAL ROLE or SET LOCAL search_path, last until commit or
+// rollback and do not leak to the pooled connection. If fn returns an
+// error, the transaction is rolled back and the error returned. fn
+// receives the backend so it can skip databases it does not apply to.
+// It may be given several times; setup functions run in registration
+// order. Reused transactions and savepoints are not set up again.
+func OnTxBegin(fn SetupFunc) Option {
+	return func(r *BaseRepo) {
+		if fn != nil {
+			r.setups = append(r.setups, fn)
+		}
+	}
+}
+
+// setUp runs the registered setup functions against a new transaction.
+func (r *BaseRepo) setUp(ctx context.Context, backend Backend, exec ExecFunc) error {
+	for _, fn := range r.setups {
+		if err := fn(ctx, backend, exec); err != nil {
+			return err
+		}
+	}
+	return nil
+}
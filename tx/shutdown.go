@@ -0,0 +1,147 @@
+package tx
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sync/atomic"
+	"time"
+)
+
+// ErrShuttingDown is returned instead of starting a new transaction once
+// Drain or Close has been called.
+var ErrShuttingDown = errors.New("tx: repository is shutting down")
+
+// drainPollInterval is how often Drain checks for in-flight
+// transactions.
+const drainPollInterval = 10 * time.Millisecond
+
+// activeTxs counts the transactions in flight, per backend.
+type activeTxs struct {
+	postgres  atomic.Int64
+	timescale atomic.Int64
+	mysql     atomic.Int64
+	sqlite    atomic.Int64
+}
+
+// counter returns the counter of backend.
+func (a *activeTxs) counter(backend Backend) *atomic.Int64 {
+	switch backend {
+	case BackendTimescale:
+		return &a.timescale
+	case BackendMySQL:
+		return &a.mysql
+	case BackendSQLite:
+		return &a.sqlite
+	}
+	return &a.postgres
+}
+
+// total returns the number of transactions in flight on all backends.
+func (a *activeTxs) total() int64 {
+	return a.postgres.Load() + a.timescale.Load() + a.mysql.Load() + a.sqlite.Load()
+}
+
+// enter accounts for a transaction about to start on backend, or
+// returns ErrShuttingDown if the repository is draining. Every
+// successful enter must be paired with leave.
+func (r *BaseRepo) enter(backend Backend) error {
+	counter := r.active.counter(backend)
+	counter.Add(1)
+
+	// Counting before checking the flag guarantees that Drain either
+	// sees this transaction or this transaction sees the flag.
+	if r.shuttingDown.Load() {
+		counter.Add(-1)
+		return ErrShuttingDown
+	}
+	return nil
+}
+
+// leave accounts for a transaction on backend that has finished.
+func (r *BaseRepo) leave(backend Backend) {
+	r.active.counter(backend).Add(-1)
+}
+
+// ActiveTransactions returns the number of PostgreSQL and TimescaleDB
+// transactions started by the repository that are still in flight.
+func (r *BaseRepo) ActiveTransactions() (postgres, timescale int) {
+	return int(r.active.postgres.Load()), int(r.active.timescale.Load())
+}
+
+// -----------------------------
+// Shutdown
+// -----------------------------
+
+// Drain stops the repository from starting new transactions and waits
+// for the ones in flight to finish.
+//
+// From the moment Drain is called, helpers that would begin a
+// transaction return ErrShuttingDown; calls reusing a transaction
+// already in flight keep working so it can complete. Drain returns once
+// no transaction is in flight, or ctx.Err() if ctx is done first.
+// Draining cannot be undone.
+func (r *BaseRepo) Drain(ctx context.Context) error {
+	r.shuttingDown.Store(true)
+	return r.wait(ctx)
+}
+
+// Close drains the repository, then closes every database handle it was
+// configured with, including read replicas.
+//
+// As with Drain, new transactions are rejected with ErrShuttingDown from
+// the moment Close is called, while those in flight may finish. Closing
+// a handle waits for the connections still in use to be released, so
+// Close stops waiting when ctx is done and returns ctx.Err(): the
+// handles are then closed in the background, as soon as the remaining
+// transactions finish. Otherwise the errors of all handles are joined.
+// The repository must not be used after Close.
+func (r *BaseRepo) Close(ctx context.Context) error {
+	drainErr := r.Drain(ctx)
+
+	closed := make(chan error, 1)
+	go func() { closed <- r.closeHandles() }()
+
+	select {
+	case err := <-closed:
+		return errors.Join(drainErr, err)
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// closeHandles closes every database handle of the repository and joins
+// their errors.
+func (r *BaseRepo) closeHandles() error {
+	var errs []error
+	dbs := []*sql.DB{r.postgresDB, r.mysqlDB, r.sqliteDB}
+	for _, rep := range r.replicas {
+		dbs = append(dbs, rep.db)
+	}
+	for _, db := range dbs {
+		if db != nil {
+			errs = append(errs, db.Close())
+		}
+	}
+	if r.timescaleDB != nil {
+		r.timescaleDB.Close()
+	}
+
+	return errors.Join(errs...)
+}
+
+// wait blocks until no transaction started by the repository is in
+// flight or ctx is done.
+func (r *BaseRepo) wait(ctx context.Context) error {
+	ticker := time.NewTicker(drainPollInterval)
+	defer ticker.Stop()
+
+	for r.active.total() > 0 {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+	return nil
+}
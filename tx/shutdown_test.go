@@ -0,0 +1,129 @@
+package tx_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/arunni/go-db-tx/tx"
+	"github.com/arunni/go-db-tx/tx/txtest"
+)
+
+func TestCloseClosesHandles(t *testing.T) {
+	spy := txtest.NewSpy()
+	r := tx.NewBaseRepo(spy.DB(), nil)
+
+	if err := r.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := spy.DB().Ping(); err == nil {
+		t.Fatal("PostgreSQL handle still open after Close")
+	}
+	if err := r.WithPostgresDBTx(context.Background(), func(context.Context) error { return nil }); !errors.Is(err, tx.ErrShuttingDown) {
+		t.Fatalf("err = %v, want ErrShuttingDown", err)
+	}
+}
+
+func TestCloseStopsWaitingWhenContextIsDone(t *testing.T) {
+	spy := txtest.NewSpy()
+	r := tx.NewBaseRepo(spy.DB(), nil)
+
+	_, _, finisher, err := r.BeginPostgresTx(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = finishWithin(t, finisher, nil) }()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	result := make(chan error, 1)
+	go func() { result <- r.Close(ctx) }()
+	select {
+	case err := <-result:
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("err = %v, want context.DeadlineExceeded", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Close did not return once ctx was done")
+	}
+}
+
+func TestDrainWaitsForInFlightTransactions(t *testing.T) {
+	spy := txtest.NewSpy()
+	r := tx.NewBaseRepo(spy.DB(), nil)
+
+	_, txCtx, finisher, err := r.BeginPostgresTx(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if postgres, timescale := r.ActiveTransactions(); postgres != 1 || timescale != 0 {
+		t.Fatalf("ActiveTransactions() = %d, %d, want 1, 0", postgres, timescale)
+	}
+
+	drained := make(chan error, 1)
+	go func() { drained <- r.Drain(context.Background()) }()
+
+	// Wait for Drain to start rejecting new transactions.
+	for r.WithPostgresDBTx(context.Background(), func(context.Context) error { return nil }) == nil {
+		time.Sleep(time.Millisecond)
+	}
+	err = r.WithPostgresDBTx(txCtx, func(context.Context) error { return nil })
+	if err != nil {
+		t.Fatalf("reusing the in-flight transaction = %v, want nil", err)
+	}
+	select {
+	case err := <-drained:
+		t.Fatalf("Drain returned %v with a transaction in flight", err)
+	default:
+	}
+
+	if err := finishWithin(t, finisher, nil); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case err := <-drained:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Drain did not return once the transaction finished")
+	}
+}
+
+func TestDrainStopsWaitingWhenContextIsDone(t *testing.T) {
+	spy := txtest.NewSpy()
+	r := tx.NewBaseRepo(spy.DB(), nil)
+
+	_, _, finisher, err := r.BeginPostgresTx(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = finishWithin(t, finisher, nil) }()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := r.Drain(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("err = %v, want context.DeadlineExceeded", err)
+	}
+}
+
+func TestShuttingDownRejectsNewTransactions(t *testing.T) {
+	spy := txtest.NewSpy()
+	r := tx.NewBaseRepo(spy.DB(), nil)
+
+	if err := r.Drain(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	err := r.WithPostgresDBTx(context.Background(), func(context.Context) error { return nil })
+	if !errors.Is(err, tx.ErrShuttingDown) {
+		t.Fatalf("WithPostgresDBTx = %v, want ErrShuttingDown", err)
+	}
+	if _, _, _, err := r.BeginPostgresTx(context.Background()); !errors.Is(err, tx.ErrShuttingDown) {
+		t.Fatalf("BeginPostgresTx = %v, want ErrShuttingDown", err)
+	}
+	if calls := spy.Calls(); len(calls) != 0 {
+		t.Fatalf("calls = %v, want none", calls)
+	}
+}
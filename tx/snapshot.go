@@ -0,0 +1,96 @@
+package tx
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// -----------------------------
+// Snapshot Transactions
+// -----------------------------
+
+// WithPostgresSnapshotTx executes the given function within a read-only,
+// deferrable, serializable PostgreSQL transaction.
+//
+// Such a transaction may wait when it starts until it can obtain a safe
+// snapshot, but then runs without taking predicate locks, never blocks or
+// aborts concurrent writers and is never cancelled with a serialization
+// failure, which suits long analytical reads. The transaction is
+// read-only: any write attempted inside fn fails with an error from the
+// server.
+//
+// If a transaction already exists in the context, it is reused as long as
+// it is serializable; otherwise ErrNestedTxOptionsConflict is returned.
+func (r *BaseRepo) WithPostgresSnapshotTx(
+	ctx context.Context,
+	fn func(ctx context.Context) error,
+) error {
+
+	opts := &sql.TxOptions{Isolation: sql.LevelSerializable, ReadOnly: true}
+	return r.withSQLTx(ctx, r.postgres(), opts, txConfig{deferrable: true}, fn)
+}
+
+// WithTimescaleSnapshotTx is the TimescaleDB counterpart of
+// WithPostgresSnapshotTx: fn runs within a read-only, deferrable,
+// serializable transaction.
+func (r *BaseRepo) WithTimescaleSnapshotTx(
+	ctx context.Context,
+	fn func(ctx context.Context) error,
+) error {
+
+	return r.WithTimescaleDBTxOpts(ctx, pgx.TxOptions{
+		IsoLevel:       pgx.Serializable,
+		AccessMode:     pgx.ReadOnly,
+		DeferrableMode: pgx.Deferrable,
+	}, fn)
+}
+
+// -----------------------------
+// Shared Snapshots
+// -----------------------------
+
+// ExportSnapshot exports the snapshot of the PostgreSQL transaction in
+// the context with pg_export_snapshot and returns its identifier, for
+// other transactions to import with WithPostgresDBTxUsingSnapshot.
+//
+// The snapshot can only be imported while the exporting transaction is
+// still open, so keep it open until every importer has started. Export
+// from a REPEATABLE READ or SERIALIZABLE transaction so that it keeps
+// seeing the same snapshot itself. ErrNoTransaction is returned if the
+// context carries no PostgreSQL transaction.
+func (r *BaseRepo) ExportSnapshot(ctx context.Context) (string, error) {
+	tx, ok := r.postgresTx(ctx)
+	if !ok {
+		return "", ErrNoTransaction
+	}
+
+	var id string
+	if err := tx.QueryRowContext(ctx, "SELECT pg_export_snapshot()").Scan(&id); err != nil {
+		return "", err
+	}
+	return id, nil
+}
+
+// WithPostgresDBTxUsingSnapshot executes the given function within a
+// REPEATABLE READ PostgreSQL transaction that sees the snapshot exported
+// by ExportSnapshot as snapshotID, so that several workers read exactly
+// the same data in parallel.
+//
+// A snapshot can only be imported by a new transaction: if one already
+// exists in the context, ErrNestedTxOptionsConflict is returned.
+func (r *BaseRepo) WithPostgresDBTxUsingSnapshot(
+	ctx context.Context,
+	snapshotID string,
+	fn func(ctx context.Context) error,
+) error {
+
+	if r.IsInPostgresTx(ctx) {
+		return fmt.Errorf("%w: a snapshot cannot be imported into an active transaction", ErrNestedTxOptionsConflict)
+	}
+
+	opts := &sql.TxOptions{Isolation: sql.LevelRepeatableRead}
+	return r.withSQLTx(ctx, r.postgres(), opts, txConfig{snapshot: snapshotID}, fn)
+}
@@ -0,0 +1,65 @@
+package tx
+
+import (
+	"context"
+	"database/sql"
+)
+
+// -----------------------------
+// SQLite Transaction
+// -----------------------------
+
+// WithSQLiteDBTx executes the given function within a SQLite transaction.
+//
+// It requires a SQLite database configured with WithSQLiteDB. If
+// a transaction already exists in the context, it will be reused.
+// The transaction is automatically committed on success or rolled
+// back on error or panic.
+func (r *BaseRepo) WithSQLiteDBTx(
+	ctx context.Context,
+	fn func(ctx context.Context) error,
+) error {
+	return r.WithSQLiteDBTxOpts(ctx, nil, fn)
+}
+
+// WithSQLiteDBTxOpts executes the given function within a SQLite
+// transaction started with the given options.
+//
+// SQLite transactions are always serializable, so only ReadOnly is
+// meaningful here; whether other isolation levels are accepted or
+// rejected depends on the driver. Reuse follows the same rules as
+// WithPostgresDBTxOpts.
+func (r *BaseRepo) WithSQLiteDBTxOpts(
+	ctx context.Context,
+	opts *sql.TxOptions,
+	fn func(ctx context.Context) error,
+) error {
+	return r.withSQLTx(ctx, r.sqlite(), opts, txConfig{}, fn)
+}
+
+// sqlite returns the SQLite database/sql backend.
+func (r *BaseRepo) sqlite() sqlBackend {
+	return sqlBackend{backend: BackendSQLite, db: r.sqliteDB, key: r.keys.sqlite}
+}
+
+// GetSQLiteTxFromContext retrieves a SQLite transaction from the context.
+func (r *BaseRepo) GetSQLiteTxFromContext(ctx context.Context) (*sql.Tx, bool) {
+	active, ok := sqlTxFromContext(ctx, r.keys.sqlite)
+	if !ok {
+		return nil, false
+	}
+	tx, ok := active.tx.(*sql.Tx)
+	return tx, ok
+}
+
+// SQLiteQueryExecutor returns a SQLite query executor.
+//
+// If a transaction exists in the context, statements run in it.
+// Otherwise, the SQLite *sql.DB instance is used.
+func (r *BaseRepo) SQLiteQueryExecutor(ctx context.Context) SQLExecutor {
+	if active, ok := sqlTxFromContext(ctx, r.keys.sqlite); ok {
+		return newTxExecutor(active)
+	}
+	r.checkContext(ctx, BackendSQLite, r.keys.sqlite)
+	return r.sqliteDB
+}
@@ -0,0 +1,32 @@
+package tx
+
+import (
+	"database/sql"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Stats is a snapshot of the connection pools of a BaseRepo.
+type Stats struct {
+	// Postgres holds the PostgreSQL pool statistics. It is the zero value
+	// if no PostgreSQL database is configured.
+	Postgres sql.DBStats
+
+	// Timescale holds the TimescaleDB pool statistics, such as
+	// AcquiredConns, IdleConns and TotalConns. It is nil if no TimescaleDB
+	// pool is configured.
+	Timescale *pgxpool.Stat
+}
+
+// Stats returns a snapshot of the connection pool statistics of both
+// databases, for example to monitor pool saturation.
+func (r *BaseRepo) Stats() Stats {
+	var stats Stats
+	if r.postgresDB != nil {
+		stats.Postgres = r.postgresDB.Stats()
+	}
+	if r.timescaleDB != nil {
+		stats.Timescale = r.timescaleDB.Stat()
+	}
+	return stats
+}
@@ -0,0 +1,25 @@
+package tx
+
+import (
+	"context"
+	"sync"
+)
+
+// TxStore returns a key/value store bound to the transaction in the
+// context, for per-transaction state such as a correlation ID or a
+// counter that any repository taking part in it can read and update.
+//
+// The store belongs to the outermost transaction: nested calls,
+// savepoints and transactions on other backends started within it all
+// share it. It is discarded with the transaction; a later transaction
+// gets a fresh, empty store. As with context keys, use unexported key
+// types to avoid collisions between packages.
+//
+// It reports false if the context carries no transaction.
+func TxStore(ctx context.Context) (*sync.Map, bool) {
+	scope, ok := scopeFromContext(ctx)
+	if !ok {
+		return nil, false
+	}
+	return scope.store, true
+}
@@ -0,0 +1,116 @@
+package tx
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"runtime"
+	"runtime/debug"
+	"strconv"
+	"sync"
+)
+
+// -----------------------------
+// Strict Context Mode
+// -----------------------------
+
+// StrictContextMode makes the executors, such as PostgresQueryExecutor
+// and TimescaleQueryExecutor, log an error with a stack trace when they
+// are called with a context that carries no transaction from a goroutine
+// that is running a transaction of the same backend, which usually means
+// a repository was passed the caller's ctx instead of the one given to
+// fn, so its statements run outside the transaction.
+//
+// The check is best-effort: it only tracks the goroutine that runs fn,
+// so work handed to other goroutines is not checked, and it never fails
+// the statement. Statements meant to run outside the transaction should
+// go through WithoutPostgresTx or WithoutTimescaleTx, which are not
+// reported. The check inspects the goroutine stack on every call made
+// without a transaction, so enable it in development and tests.
+func StrictContextMode(enabled bool) Option {
+	return func(r *BaseRepo) {
+		if !enabled {
+			r.strict = nil
+			return
+		}
+		r.strict = &openTxs{counts: make(map[openTxKey]int)}
+	}
+}
+
+// openTxs counts the transactions each goroutine is running, per backend.
+type openTxs struct {
+	mu     sync.Mutex
+	counts map[openTxKey]int
+}
+
+// openTxKey identifies the transactions of one backend run by one
+// goroutine.
+type openTxKey struct {
+	goroutine uint64
+	backend   Backend
+}
+
+// trackOpen records that the calling goroutine runs a transaction of
+// backend until the returned function is called. It does nothing unless
+// StrictContextMode is enabled.
+func (r *BaseRepo) trackOpen(backend Backend) func() {
+	t := r.strict
+	if t == nil {
+		return func() {}
+	}
+
+	k := openTxKey{goroutine: goroutineID(), backend: backend}
+	t.mu.Lock()
+	t.counts[k]++
+	t.mu.Unlock()
+
+	return func() {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		if t.counts[k]--; t.counts[k] <= 0 {
+			delete(t.counts, k)
+		}
+	}
+}
+
+// checkContext reports an executor of backend being called with a
+// context that lacks its transaction while the calling goroutine runs
+// one. key is the context key of the backend's transaction.
+func (r *BaseRepo) checkContext(ctx context.Context, backend Backend, key contextKey) {
+	t := r.strict
+	if t == nil || ctx.Value(detachedKey(key)) != nil {
+		return
+	}
+
+	t.mu.Lock()
+	open := t.counts[openTxKey{goroutine: goroutineID(), backend: backend}]
+	t.mu.Unlock()
+	if open == 0 {
+		return
+	}
+
+	r.logger.LogAttrs(ctx, slog.LevelError,
+		"statement runs outside the open transaction: the context does not carry it",
+		slog.String("backend", string(backend)),
+		slog.String("stack", string(debug.Stack())),
+	)
+}
+
+// detachedKey marks a context from which the transaction stored under
+// key was removed on purpose, by WithoutPostgresTx or WithoutTimescaleTx.
+func detachedKey(key contextKey) contextKey {
+	return key + "_detached"
+}
+
+// goroutineID returns the ID of the calling goroutine, parsed from the
+// header of its stack trace ("goroutine 42 [running]:").
+func goroutineID() uint64 {
+	var buf [64]byte
+	b := buf[:runtime.Stack(buf[:], false)]
+	b = bytes.TrimPrefix(b, []byte("goroutine "))
+	if i := bytes.IndexByte(b, ' '); i >= 0 {
+		b = b[:i]
+	}
+	id, _ := strconv.ParseUint(string(b), 10, 64)
+	return id
+}
@@ -0,0 +1,59 @@
+package tx_test
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/arunni/go-db-tx/tx"
+	"github.com/arunni/go-db-tx/tx/txtest"
+)
+
+func TestStrictContextMode(t *testing.T) {
+	spy := txtest.NewSpy()
+	var logs bytes.Buffer
+	r := tx.NewBaseRepo(spy.DB(), nil,
+		tx.StrictContextMode(true),
+		tx.WithLogger(slog.New(slog.NewTextHandler(&logs, nil))),
+	)
+	outer := context.Background()
+
+	err := r.WithPostgresDBTx(outer, func(ctx context.Context) error {
+		if _, err := r.PostgresQueryExecutor(ctx).ExecContext(ctx, "UPDATE in tx"); err != nil {
+			return err
+		}
+		if logs.Len() != 0 {
+			t.Fatalf("statement in the transaction logged %q", logs.String())
+		}
+
+		err := r.WithoutPostgresTx(ctx, func(ctx context.Context) error {
+			_, err := r.PostgresQueryExecutor(ctx).ExecContext(ctx, "INSERT audit")
+			return err
+		})
+		if err != nil {
+			return err
+		}
+		if logs.Len() != 0 {
+			t.Fatalf("statement detached with WithoutPostgresTx logged %q", logs.String())
+		}
+
+		_, err = r.PostgresQueryExecutor(outer).ExecContext(outer, "UPDATE wrong ctx")
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(logs.String(), "statement runs outside the open transaction") {
+		t.Fatalf("logs = %q, want the wrong context reported", logs.String())
+	}
+
+	logs.Reset()
+	if _, err := r.PostgresQueryExecutor(outer).ExecContext(outer, "UPDATE after"); err != nil {
+		t.Fatal(err)
+	}
+	if logs.Len() != 0 {
+		t.Fatalf("statement with no transaction open logged %q", logs.String())
+	}
+}
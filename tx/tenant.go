@@ -0,0 +1,61 @@
+package tx
+
+import (
+	"context"
+	"fmt"
+)
+
+// tenantKey stores the tenant of the current unit of work.
+const tenantKey contextKey = "tx_tenant"
+
+// tenantSetting is the configuration parameter row-level security
+// policies read the tenant from, with current_setting('app.tenant_id').
+const tenantSetting = "app.tenant_id"
+
+// TenantFromContext returns the tenant set by WithPostgresTenantTx, if
+// any.
+func TenantFromContext(ctx context.Context) (string, bool) {
+	tenantID, ok := ctx.Value(tenantKey).(string)
+	return tenantID, ok
+}
+
+// WithPostgresTenantTx executes the given function within a PostgreSQL
+// transaction scoped to tenantID.
+//
+// Right after the transaction begins, app.tenant_id is set to tenantID
+// for the rest of the transaction, the equivalent of SET LOCAL, so
+// row-level security policies using current_setting('app.tenant_id')
+// apply to everything fn does. The setting is reset when the transaction
+// commits or rolls back. The tenant is also stored in the context, see
+// TenantFromContext.
+//
+// Nested calls for the same tenant reuse the transaction. Reusing an
+// active transaction for another tenant, or one started without a
+// tenant, is rejected with ErrNestedTxOptionsConflict, since the setting
+// would leak into work outside fn.
+func (r *BaseRepo) WithPostgresTenantTx(
+	ctx context.Context,
+	tenantID string,
+	fn func(ctx context.Context) error,
+) error {
+
+	if r.IsInPostgresTx(ctx) {
+		active, ok := TenantFromContext(ctx)
+		if !ok {
+			return fmt.Errorf("%w: requested tenant %q, active transaction has no tenant", ErrNestedTxOptionsConflict, tenantID)
+		}
+		if active != tenantID {
+			return fmt.Errorf("%w: requested tenant %q, active transaction tenant %q", ErrNestedTxOptionsConflict, tenantID, active)
+		}
+		return r.WithPostgresDBTx(ctx, fn)
+	}
+
+	ctx = context.WithValue(ctx, tenantKey, tenantID)
+	return r.WithPostgresDBTx(ctx, func(ctx context.Context) error {
+		tx, _ := r.postgresTx(ctx)
+		if _, err := tx.ExecContext(ctx, "SELECT set_config($1, $2, true)", tenantSetting, tenantID); err != nil {
+			return err
+		}
+		return fn(ctx)
+	})
+}
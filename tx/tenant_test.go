@@ -0,0 +1,54 @@
+package tx_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/arunni/go-db-tx/tx"
+	"github.com/arunni/go-db-tx/tx/txtest"
+)
+
+func TestTenantTx(t *testing.T) {
+	spy := txtest.NewSpy()
+	r := tx.NewBaseRepo(spy.DB(), nil)
+
+	err := r.WithPostgresTenantTx(context.Background(), "acme", func(ctx context.Context) error {
+		if tenant, ok := tx.TenantFromContext(ctx); !ok || tenant != "acme" {
+			t.Fatalf("TenantFromContext = %q, %v, want acme", tenant, ok)
+		}
+		return r.WithPostgresTenantTx(ctx, "acme", func(ctx context.Context) error {
+			_, err := r.PostgresQueryExecutor(ctx).ExecContext(ctx, "UPDATE a")
+			return err
+		})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assertQueries(t, spy, "SELECT set_config($1, $2, true)", "UPDATE a")
+	if args := spy.Calls()[1].Args; len(args) != 2 || args[0] != "app.tenant_id" || args[1] != "acme" {
+		t.Fatalf("set_config args = %v, want [app.tenant_id acme]", args)
+	}
+	assertKinds(t, spy, txtest.CallBegin, txtest.CallExec, txtest.CallExec, txtest.CallCommit)
+}
+
+func TestTenantTxConflict(t *testing.T) {
+	spy := txtest.NewSpy()
+	r := tx.NewBaseRepo(spy.DB(), nil)
+	ctx := context.Background()
+
+	err := r.WithPostgresTenantTx(ctx, "acme", func(ctx context.Context) error {
+		return r.WithPostgresTenantTx(ctx, "other", func(context.Context) error { return nil })
+	})
+	if !errors.Is(err, tx.ErrNestedTxOptionsConflict) {
+		t.Fatalf("other tenant: err = %v, want ErrNestedTxOptionsConflict", err)
+	}
+
+	err = r.WithPostgresDBTx(ctx, func(ctx context.Context) error {
+		return r.WithPostgresTenantTx(ctx, "acme", func(context.Context) error { return nil })
+	})
+	if !errors.Is(err, tx.ErrNestedTxOptionsConflict) {
+		t.Fatalf("no tenant: err = %v, want ErrNestedTxOptionsConflict", err)
+	}
+}
@@ -0,0 +1,71 @@
+package tx_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/arunni/go-db-tx/tx"
+	"github.com/arunni/go-db-tx/tx/txtest"
+)
+
+func TestPostgresTxTimeout(t *testing.T) {
+	spy := txtest.NewSpy()
+	r := tx.NewBaseRepo(spy.DB(), nil)
+
+	err := r.WithPostgresDBTxTimeout(context.Background(), 20*time.Millisecond, func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("err = %v, want context.DeadlineExceeded", err)
+	}
+	assertQueries(t, spy, "SET LOCAL statement_timeout = 20")
+	if calls := spy.Calls(); calls[len(calls)-1].Kind == txtest.CallCommit {
+		t.Fatal("timed-out transaction was committed")
+	}
+}
+
+func TestPostgresTxTimeoutCommits(t *testing.T) {
+	spy := txtest.NewSpy()
+	r := tx.NewBaseRepo(spy.DB(), nil)
+
+	err := r.WithPostgresDBTxTimeout(context.Background(), time.Second, func(ctx context.Context) error {
+		if _, ok := ctx.Deadline(); !ok {
+			t.Fatal("fn context has no deadline")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertKinds(t, spy, txtest.CallBegin, txtest.CallExec, txtest.CallCommit)
+}
+
+func TestPostgresTxTimeoutReusesActiveTransaction(t *testing.T) {
+	spy := txtest.NewSpy()
+	r := tx.NewBaseRepo(spy.DB(), nil)
+
+	err := r.WithPostgresDBTx(context.Background(), func(ctx context.Context) error {
+		return r.WithPostgresDBTxTimeout(ctx, time.Second, func(inner context.Context) error {
+			if !r.IsInPostgresTx(inner) {
+				t.Fatal("nested call did not reuse the transaction")
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertKinds(t, spy, txtest.CallBegin, txtest.CallCommit)
+}
+
+func TestTimescaleTxTimeoutNotConfigured(t *testing.T) {
+	r := tx.NewBaseRepo(nil, nil)
+
+	err := r.WithTimescaleDBTxTimeout(context.Background(), time.Second, func(context.Context) error { return nil })
+	if !errors.Is(err, tx.ErrTimescaleNotConfigured) {
+		t.Fatalf("err = %v, want ErrTimescaleNotConfigured", err)
+	}
+}
@@ -0,0 +1,133 @@
+package tx
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// -----------------------------
+// TimescaleDB Bulk Operations
+// -----------------------------
+
+// TimescaleSendBatch sends all queued queries of b in a single round trip.
+//
+// If a transaction exists in the context, the batch runs within it;
+// otherwise it runs on the base *pgxpool.Pool. The caller must close the
+// returned BatchResults before issuing other queries on the transaction.
+// Without a TimescaleDB pool, every result reports
+// ErrTimescaleNotConfigured.
+func (r *BaseRepo) TimescaleSendBatch(ctx context.Context, b *pgx.Batch) pgx.BatchResults {
+	if tx, ok := r.GetTimescaleTx(ctx); ok {
+		return tx.SendBatch(ctx, b)
+	}
+	r.checkContext(ctx, BackendTimescale, r.keys.timescale)
+	if r.timescaleDB == nil {
+		return errBatchResults{err: ErrTimescaleNotConfigured}
+	}
+	return r.timescaleDB.SendBatch(ctx, b)
+}
+
+// errBatchResults is a pgx.BatchResults whose every result is err.
+type errBatchResults struct {
+	err error
+}
+
+func (e errBatchResults) Exec() (pgconn.CommandTag, error) { return pgconn.CommandTag{}, e.err }
+func (e errBatchResults) Query() (pgx.Rows, error)         { return nil, e.err }
+func (e errBatchResults) QueryRow() pgx.Row                { return errRow(e) }
+func (e errBatchResults) Close() error                     { return e.err }
+
+// errRow is a pgx.Row whose Scan returns err.
+type errRow struct {
+	err error
+}
+
+func (e errRow) Scan(...any) error { return e.err }
+
+// TimescaleCopyFrom bulk-loads rows from src into tableName using the
+// COPY protocol and returns the number of rows copied.
+//
+// Executor selection follows TimescaleQueryExecutor: within a
+// WithTimescaleDBTx the rows are copied in the active transaction, so
+// they all land on commit or all disappear on rollback; otherwise the
+// base *pgxpool.Pool is used.
+func (r *BaseRepo) TimescaleCopyFrom(
+	ctx context.Context,
+	tableName pgx.Identifier,
+	columns []string,
+	src pgx.CopyFromSource,
+) (int64, error) {
+
+	if tx, ok := r.GetTimescaleTx(ctx); ok {
+		return tx.CopyFrom(ctx, tableName, columns, src)
+	}
+	r.checkContext(ctx, BackendTimescale, r.keys.timescale)
+	if r.timescaleDB == nil {
+		return 0, ErrTimescaleNotConfigured
+	}
+	return r.timescaleDB.CopyFrom(ctx, tableName, columns, src)
+}
+
+// TimescaleExecSimple executes sql with pgx's simple protocol, for
+// statements the extended protocol rejects, such as several statements
+// in one string ("cannot insert multiple commands into a prepared
+// statement") or some DDL.
+//
+// If a transaction exists in the context, the statement runs within it;
+// otherwise it runs on the base *pgxpool.Pool. With the simple protocol,
+// args are interpolated into sql by pgx on the client.
+func (r *BaseRepo) TimescaleExecSimple(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error) {
+	args = append([]any{pgx.QueryExecModeSimpleProtocol}, args...)
+	return r.TimescaleQueryExecutor(ctx).Exec(ctx, sql, args...)
+}
+
+// -----------------------------
+// TimescaleDB Connections
+// -----------------------------
+
+// WithTimescaleConn acquires a connection from the TimescaleDB pool,
+// passes it to fn and releases it once fn returns or panics, for
+// session-scoped work that needs one pinned connection outside a
+// transaction, such as temporary tables or LISTEN.
+//
+// Acquiring waits for a free connection until ctx is done. The
+// connection is separate from any transaction in the context; statements
+// meant to run on it must use conn. A connection released while a
+// transaction is still open on it is closed by the pool instead of being
+// reused, but session settings and temporary tables made by fn otherwise
+// persist with the connection, so fn should undo them.
+func (r *BaseRepo) WithTimescaleConn(
+	ctx context.Context,
+	fn func(ctx context.Context, conn *pgxpool.Conn) error,
+) error {
+
+	if r.timescaleDB == nil {
+		return ErrTimescaleNotConfigured
+	}
+
+	conn, err := r.timescaleDB.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	return fn(ctx, conn)
+}
+
+// -----------------------------
+// TimescaleDB Statement Cache
+// -----------------------------
+
+// ConfigureTimescaleStatementCache sets the capacity of pgx's
+// per-connection prepared statement cache on cfg, before the pool is
+// created with pgxpool.NewWithConfig. pgx caches statements by default,
+// up to 512 per connection; the function also restores
+// QueryExecModeCacheStatement if another mode was selected, for example
+// by default_query_exec_mode in the connection string.
+func ConfigureTimescaleStatementCache(cfg *pgxpool.Config, capacity int) {
+	cfg.ConnConfig.DefaultQueryExecMode = pgx.QueryExecModeCacheStatement
+	cfg.ConnConfig.StatementCacheCapacity = capacity
+}
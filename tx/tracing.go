@@ -0,0 +1,81 @@
+package tx
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+// tracerName is the instrumentation scope used for the default tracer.
+const tracerName = "github.com/arunni/go-db-tx/tx"
+
+// attemptKey stores the retry attempt a transaction is started for.
+const attemptKey contextKey = "tx_attempt"
+
+// Transaction outcomes recorded on spans.
+const (
+	outcomeCommitted  = "committed"
+	outcomeRolledBack = "rolled_back"
+)
+
+// dbSystem returns the OpenTelemetry db.system value for the backend.
+func (b Backend) dbSystem() string {
+	switch b {
+	case BackendMySQL:
+		return "mysql"
+	case BackendSQLite:
+		return "sqlite"
+	}
+	return "postgresql"
+}
+
+// defaultTracer returns the tracer used when none is configured.
+func defaultTracer() trace.Tracer {
+	return noop.NewTracerProvider().Tracer(tracerName)
+}
+
+// withAttempt records the retry attempt number in the context.
+func withAttempt(ctx context.Context, attempt int) context.Context {
+	return context.WithValue(ctx, attemptKey, attempt)
+}
+
+// attemptFromContext returns the retry attempt number, defaulting to 1.
+func attemptFromContext(ctx context.Context) int {
+	if attempt, ok := ctx.Value(attemptKey).(int); ok {
+		return attempt
+	}
+	return 1
+}
+
+// startSpan starts the span covering a new transaction on backend.
+func (r *BaseRepo) startSpan(ctx context.Context, backend Backend, label string) (context.Context, trace.Span) {
+	attrs := []attribute.KeyValue{
+		attribute.String("db.system", backend.dbSystem()),
+		attribute.String("tx.backend", string(backend)),
+		attribute.Int("tx.attempt", attemptFromContext(ctx)),
+	}
+	if label != "" {
+		attrs = append(attrs, attribute.String("tx.label", label))
+	}
+
+	return r.tracer.Start(ctx, "tx."+string(backend),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attrs...),
+	)
+}
+
+// endSpan records the outcome of a transaction and ends its span.
+// A nil err means the transaction committed.
+func endSpan(span trace.Span, err error) {
+	if err == nil {
+		span.SetAttributes(attribute.String("tx.outcome", outcomeCommitted))
+	} else {
+		span.SetAttributes(attribute.String("tx.outcome", outcomeRolledBack))
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
@@ -0,0 +1,394 @@
+package tx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ErrTwoPhaseInDoubt is returned by WithTwoPhaseCommit when both
+// transactions were prepared but could not both be committed. The
+// remaining prepared transactions are committed by
+// ResolveInDoubtTransactions.
+var ErrTwoPhaseInDoubt = errors.New("tx: two-phase transaction left in doubt")
+
+// undefinedObject is the SQLSTATE of ROLLBACK PREPARED for a
+// transaction that is not prepared.
+const undefinedObject = "42704"
+
+// preparedGID returns the global identifier used for the backend's half
+// of a two-phase transaction.
+func preparedGID(gid string, backend Backend) string {
+	return gid + "." + string(backend)
+}
+
+// quoteLiteral quotes s as a SQL string literal.
+func quoteLiteral(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+// -----------------------------
+// Two-Phase Commit
+// -----------------------------
+
+// WithTwoPhaseCommit executes the given function within both a PostgreSQL
+// and a TimescaleDB transaction and commits them with two-phase commit.
+//
+// After fn succeeds, both transactions are prepared with PREPARE
+// TRANSACTION under identifiers derived from gid, then committed with
+// COMMIT PREPARED, which keeps the window in which only one side is
+// committed as small as possible. If fn fails or either prepare fails,
+// both transactions are rolled back, including a side that was already
+// prepared. Once both are prepared the outcome is commit: if a COMMIT
+// PREPARED fails, ErrTwoPhaseInDoubt is returned and the remaining
+// prepared transactions must be finished with ResolveInDoubtTransactions.
+//
+// gid must be unique among in-flight transactions. Both servers need
+// max_prepared_transactions > 0. Callbacks registered with
+// RegisterAfterCommit or RegisterAfterRollback inside fn run once the
+// outcome of both sides is known. Hooks and metrics report a side as
+// committed only once its COMMIT PREPARED succeeded; a side left in
+// doubt is reported to OnRollback with ErrTwoPhaseInDoubt. Unlike other
+// helpers, an existing transaction in the context cannot be reused and
+// is reported as ErrNestedTxOptionsConflict.
+func (r *BaseRepo) WithTwoPhaseCommit(
+	ctx context.Context,
+	gid string,
+	fn func(ctx context.Context) error,
+) (err error) {
+
+	if r.IsInPostgresTx(ctx) {
+		return fmt.Errorf("%w: two-phase commit cannot reuse an active postgres transaction", ErrNestedTxOptionsConflict)
+	}
+	if r.IsInTimescaleTx(ctx) {
+		return fmt.Errorf("%w: two-phase commit cannot reuse an active timescale transaction", ErrNestedTxOptionsConflict)
+	}
+	if !r.postgres().configured() {
+		return ErrPostgresNotConfigured
+	}
+	if r.timescaleDB == nil {
+		return ErrTimescaleNotConfigured
+	}
+
+	if err := r.enter(BackendPostgres); err != nil {
+		return err
+	}
+	defer r.leave(BackendPostgres)
+	if err := r.enter(BackendTimescale); err != nil {
+		return err
+	}
+	defer r.leave(BackendTimescale)
+
+	pgGID := quoteLiteral(preparedGID(gid, BackendPostgres))
+	tsGID := quoteLiteral(preparedGID(gid, BackendTimescale))
+
+	// Both transactions are begun here rather than through WithPostgresDBTx
+	// and WithTimescaleDBTx, whose commit path must not run on a prepared
+	// transaction.
+	tsCtx, tsRun := r.startRun(ctx, BackendTimescale)
+	tsTx, err := r.beginTimescale(tsCtx, r.timescaleOpts)
+	if err != nil {
+		tsRun.beginFailed(err)
+		return err
+	}
+	tsRun.begun()
+	defer r.trackOpen(BackendTimescale)()
+
+	pgCtx, pgRun := r.startRun(ctx, BackendPostgres)
+	pgTx, release, err := r.beginSQL(pgCtx, r.postgres(), r.postgresOpts)
+	if err != nil {
+		pgRun.beginFailed(err)
+		rbCtx, cancel := r.rollbackContext(ctx)
+		defer cancel()
+		rbErr := rollbackErr(tsTx.Rollback(rbCtx))
+		tsRun.rolledBack(err, rbErr)
+		return rollbackResult(err, rbErr)
+	}
+	defer release()
+	pgRun.begun()
+	defer r.trackOpen(BackendPostgres)()
+
+	// Once PostgreSQL is prepared, the transactions are finished on a
+	// context the caller cannot cancel, so that no side is left prepared
+	// by a cancellation.
+	finishCtx := context.WithoutCancel(ctx)
+	var pgPrepared, finished bool
+
+	txCtx, scope := newScopeContext(pgCtx, r.clock)
+	rollback := func(cause error) error {
+		finished = true
+		var pgErr error
+		if pgPrepared {
+			_, pgErr = r.postgresPrimary().ExecContext(finishCtx, "ROLLBACK PREPARED "+pgGID)
+		} else {
+			pgErr = rollbackErr(pgTx.Rollback())
+		}
+		pgRun.rolledBack(cause, pgErr)
+
+		rbCtx, cancel := r.rollbackContext(ctx)
+		defer cancel()
+		tsErr := rollbackErr(tsTx.Rollback(rbCtx))
+		tsRun.rolledBack(cause, tsErr)
+
+		scope.rolledBack()
+		return rollbackResult(cause, errors.Join(pgErr, tsErr))
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			if finished {
+				panic(p)
+			}
+			err = rollback(r.recovered(pgRun, p))
+			if !r.recoverPanics {
+				panic(p)
+			}
+		}
+	}()
+
+	txCtx = context.WithValue(txCtx, r.keys.timescale, &timescaleTx{tx: tsTx, opts: r.timescaleOpts, scope: scope})
+	txCtx = withOutermost(txCtx, true)
+	tsRun.bind(txCtx)
+
+	pgState := &sqlTx{tx: pgTx, scope: scope}
+	if r.cacheStatements {
+		pgState.stmts = newStmtCache()
+	}
+	if r.logFailedStmts {
+		pgState.failed = new(failedStatement)
+		pgRun.failed = pgState.failed
+	}
+	if r.postgresOpts != nil {
+		pgState.opts = *r.postgresOpts
+	}
+	txCtx = context.WithValue(txCtx, r.keys.postgres, pgState)
+	txCtx = context.WithValue(txCtx, loggerKey, pgRun.txLogger())
+	pgRun.bind(withIntendedNesting(txCtx, BackendPostgres))
+
+	tsExec := func(ctx context.Context, query string, args ...any) error {
+		_, err := tsTx.Exec(ctx, query, args...)
+		return err
+	}
+	pgExec := func(ctx context.Context, query string, args ...any) error {
+		_, err := pgTx.ExecContext(ctx, query, args...)
+		return err
+	}
+	if err := r.labelConfig(txConfig{}, BackendTimescale, tsRun.label).apply(txCtx, func(ctx context.Context, query string) error {
+		return tsExec(ctx, query)
+	}); err != nil {
+		return rollback(err)
+	}
+	if err := r.labelConfig(txConfig{}, BackendPostgres, pgRun.label).apply(txCtx, func(ctx context.Context, query string) error {
+		return pgExec(ctx, query)
+	}); err != nil {
+		return rollback(err)
+	}
+	if err := r.setUp(txCtx, BackendTimescale, tsExec); err != nil {
+		return rollback(err)
+	}
+	if err := r.setUp(txCtx, BackendPostgres, pgExec); err != nil {
+		return rollback(err)
+	}
+
+	if err := fn(txCtx); err != nil {
+		return rollback(err)
+	}
+
+	if err := scope.runBeforeCommit(txCtx); err != nil {
+		return rollback(err)
+	}
+
+	if scope.isRollbackOnly() {
+		return rollback(ErrRollbackOnly)
+	}
+
+	// Do not prepare work whose context is already done
+	if err := txCtx.Err(); err != nil {
+		return rollback(err)
+	}
+
+	if _, err := pgTx.ExecContext(finishCtx, "PREPARE TRANSACTION "+pgGID); err != nil {
+		return rollback(err)
+	}
+	pgPrepared = true
+
+	// PREPARE ended the transaction on the server: finishing pgTx only
+	// releases its connection.
+	_ = pgTx.Rollback()
+
+	// PostgreSQL is prepared: it is rolled back if TimescaleDB cannot be.
+	if _, err := tsTx.Exec(finishCtx, "PREPARE TRANSACTION "+tsGID); err != nil {
+		// The server may have prepared it even though the client saw an
+		// error, for example when the connection dropped right after.
+		_, tsErr := r.timescaleDB.Exec(finishCtx, "ROLLBACK PREPARED "+tsGID)
+		if sqlState(tsErr) == undefinedObject {
+			tsErr = nil
+		}
+		return errors.Join(rollback(err), tsErr)
+	}
+	rbCtx, cancel := r.rollbackContext(ctx)
+	defer cancel()
+	_ = tsTx.Rollback(rbCtx)
+
+	// Both sides are prepared, so the transaction must now commit.
+	finished = true
+	if _, err := r.postgresPrimary().ExecContext(finishCtx, "COMMIT PREPARED "+pgGID); err != nil {
+		err = fmt.Errorf("%w: gid %q: %w", ErrTwoPhaseInDoubt, gid, err)
+		pgRun.inDoubt(err)
+		tsRun.inDoubt(err)
+		return err
+	}
+	pgRun.committed()
+
+	if _, err := r.timescaleDB.Exec(finishCtx, "COMMIT PREPARED "+tsGID); err != nil {
+		err = fmt.Errorf("%w: gid %q: %w", ErrTwoPhaseInDoubt, gid, err)
+		tsRun.inDoubt(err)
+		return err
+	}
+	tsRun.committed()
+
+	scope.committed()
+	return nil
+}
+
+// -----------------------------
+// Two-Phase Recovery
+// -----------------------------
+
+// InDoubtTx describes one side of a two-phase transaction started by
+// WithTwoPhaseCommit that is still prepared.
+type InDoubtTx struct {
+	// GID is the identifier passed to WithTwoPhaseCommit.
+	GID string
+
+	// Backend is the database holding the prepared transaction.
+	Backend Backend
+
+	// Prepared is when the transaction was prepared.
+	Prepared time.Time
+}
+
+// ListInDoubtTransactions returns the prepared transactions left behind
+// by WithTwoPhaseCommit in the current database of both backends.
+func (r *BaseRepo) ListInDoubtTransactions(ctx context.Context) ([]InDoubtTx, error) {
+	const query = `SELECT gid, prepared FROM pg_prepared_xacts WHERE database = current_database()`
+
+	var inDoubt []InDoubtTx
+	collect := func(backend Backend, gid string, prepared time.Time) {
+		if base, ok := strings.CutSuffix(gid, "."+string(backend)); ok {
+			inDoubt = append(inDoubt, InDoubtTx{GID: base, Backend: backend, Prepared: prepared})
+		}
+	}
+
+	if r.postgres().configured() {
+		rows, err := r.postgresPrimary().QueryContext(ctx, query)
+		if err != nil {
+			return nil, err
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var gid string
+			var prepared time.Time
+			if err := rows.Scan(&gid, &prepared); err != nil {
+				return nil, err
+			}
+			collect(BackendPostgres, gid, prepared)
+		}
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+	}
+
+	if r.timescaleDB != nil {
+		rows, err := r.timescaleDB.Query(ctx, query)
+		if err != nil {
+			return nil, err
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var gid string
+			var prepared time.Time
+			if err := rows.Scan(&gid, &prepared); err != nil {
+				return nil, err
+			}
+			collect(BackendTimescale, gid, prepared)
+		}
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+	}
+
+	return inDoubt, nil
+}
+
+// ResolveInDoubtTransactions finishes the two-phase transactions left
+// prepared after a crash or a failed COMMIT PREPARED.
+//
+// The TimescaleDB side is only prepared once the PostgreSQL side is, and
+// WithTwoPhaseCommit rolls back a TimescaleDB side whose PREPARE reported
+// an error, in case the server prepared it anyway. A prepared TimescaleDB
+// side therefore means the transaction had decided to commit: both
+// remaining sides are committed. A prepared PostgreSQL side on its own
+// means TimescaleDB never prepared, and it is rolled back. If that
+// rollback of the TimescaleDB side failed too, WithTwoPhaseCommit returns
+// its error and the side must be rolled back by hand before resolving.
+//
+// Only transactions prepared more than olderThan ago are considered, so
+// that transactions still being committed by a live process are left
+// alone. The errors of all failed resolutions are joined.
+func (r *BaseRepo) ResolveInDoubtTransactions(ctx context.Context, olderThan time.Duration) error {
+	inDoubt, err := r.ListInDoubtTransactions(ctx)
+	if err != nil {
+		return err
+	}
+
+	// A transaction is skipped as soon as one of its sides is recent.
+	sides := make(map[string]map[Backend]bool)
+	recent := make(map[string]bool)
+	cutoff := r.clock.Now().Add(-olderThan)
+	for _, t := range inDoubt {
+		if t.Prepared.After(cutoff) {
+			recent[t.GID] = true
+		}
+		if sides[t.GID] == nil {
+			sides[t.GID] = make(map[Backend]bool)
+		}
+		sides[t.GID][t.Backend] = true
+	}
+
+	gids := make([]string, 0, len(sides))
+	for gid := range sides {
+		if !recent[gid] {
+			gids = append(gids, gid)
+		}
+	}
+	sort.Strings(gids)
+
+	var errs []error
+	for _, gid := range gids {
+		pgGID := quoteLiteral(preparedGID(gid, BackendPostgres))
+		tsGID := quoteLiteral(preparedGID(gid, BackendTimescale))
+
+		if !sides[gid][BackendTimescale] {
+			if _, err := r.postgresPrimary().ExecContext(ctx, "ROLLBACK PREPARED "+pgGID); err != nil {
+				errs = append(errs, fmt.Errorf("tx: rollback prepared %q on postgres: %w", gid, err))
+			}
+			continue
+		}
+
+		if sides[gid][BackendPostgres] {
+			if _, err := r.postgresPrimary().ExecContext(ctx, "COMMIT PREPARED "+pgGID); err != nil {
+				errs = append(errs, fmt.Errorf("tx: commit prepared %q on postgres: %w", gid, err))
+				continue
+			}
+		}
+		if _, err := r.timescaleDB.Exec(ctx, "COMMIT PREPARED "+tsGID); err != nil {
+			errs = append(errs, fmt.Errorf("tx: commit prepared %q on timescale: %w", gid, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,39 @@
+package tx_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/arunni/go-db-tx/tx"
+	"github.com/arunni/go-db-tx/tx/txtest"
+)
+
+func TestTwoPhaseCommitNeedsTimescale(t *testing.T) {
+	spy := txtest.NewSpy()
+	r := tx.NewBaseRepo(spy.DB(), nil)
+
+	err := r.WithTwoPhaseCommit(context.Background(), "order-1", func(context.Context) error { return nil })
+	if !errors.Is(err, tx.ErrTimescaleNotConfigured) {
+		t.Fatalf("err = %v, want ErrTimescaleNotConfigured", err)
+	}
+	if calls := spy.Calls(); len(calls) != 0 {
+		t.Fatalf("calls = %v, want none", calls)
+	}
+}
+
+func TestTwoPhaseCommitRejectsActiveTransaction(t *testing.T) {
+	spy := txtest.NewSpy()
+	r := tx.NewBaseRepo(spy.DB(), nil)
+
+	called := false
+	err := r.WithPostgresDBTx(context.Background(), func(ctx context.Context) error {
+		return r.WithTwoPhaseCommit(ctx, "order-1", func(context.Context) error {
+			called = true
+			return nil
+		})
+	})
+	if !errors.Is(err, tx.ErrNestedTxOptionsConflict) || called {
+		t.Fatalf("err = %v, called = %v, want ErrNestedTxOptionsConflict without calling fn", err, called)
+	}
+}
@@ -0,0 +1,155 @@
+package tx
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// txConfig holds per-transaction settings that are applied with
+// SET LOCAL right after a transaction begins. SET LOCAL settings last
+// until the end of the transaction and are reset on commit or rollback.
+type txConfig struct {
+	// deferrable issues SET TRANSACTION DEFERRABLE, which database/sql
+	// has no option for. It must precede any query in the transaction.
+	deferrable bool
+
+	// snapshot imports the snapshot with the given identifier with SET
+	// TRANSACTION SNAPSHOT. It too must precede any query.
+	snapshot string
+
+	statementTimeout time.Duration
+	lockTimeout      time.Duration
+	idleTimeout      time.Duration
+	applicationName  string
+}
+
+// TxOption configures a single transaction started by a *Opts variant,
+// such as WithPostgresDBTxOpts. Options are applied with SET LOCAL right
+// after BEGIN and are ignored when an active transaction is reused.
+type TxOption func(*txConfig)
+
+// StatementTimeout bounds every statement in the transaction to d by
+// issuing SET LOCAL statement_timeout, rounded up to milliseconds. A
+// statement exceeding it fails with a query-canceled error, detected
+// with IsStatementTimeout. Zero keeps the server's setting.
+func StatementTimeout(d time.Duration) TxOption {
+	return func(c *txConfig) {
+		c.statementTimeout = d
+	}
+}
+
+// LockTimeout bounds how long any statement in the transaction waits
+// for a lock to d by issuing SET LOCAL lock_timeout, rounded up to
+// milliseconds, so that contended transactions fail fast instead of
+// blocking. A statement that gives up waiting fails with SQLSTATE 55P03,
+// detected with IsLockTimeout. Zero keeps the server's setting.
+func LockTimeout(d time.Duration) TxOption {
+	return func(c *txConfig) {
+		c.lockTimeout = d
+	}
+}
+
+// IdleInTransactionTimeout makes the server terminate the session when
+// the transaction stays idle, with no statement running, for longer than
+// d, by issuing SET LOCAL idle_in_transaction_session_timeout, rounded
+// up to milliseconds. It bounds how long fn may spend on other work
+// between statements, so that a stuck caller loses its connection rather
+// than leave it idle in transaction with its locks held. The next
+// statement then fails with SQLSTATE 25P03, detected with
+// IsIdleInTransactionTimeout, or, if the server's message was lost with
+// the connection, with an error detected by IsConnectionError. Zero
+// keeps the server's setting.
+func IdleInTransactionTimeout(d time.Duration) TxOption {
+	return func(c *txConfig) {
+		c.idleTimeout = d
+	}
+}
+
+// ApplicationName sets application_name for the duration of the
+// transaction with SET LOCAL, so that pg_stat_activity shows which
+// operation holds it. PostgreSQL truncates names longer than 63 bytes.
+func ApplicationName(name string) TxOption {
+	return func(c *txConfig) {
+		c.applicationName = name
+	}
+}
+
+// newTxConfig returns the configuration built from opts.
+func newTxConfig(opts []TxOption) txConfig {
+	var c txConfig
+	for _, opt := range opts {
+		opt(&c)
+	}
+	return c
+}
+
+// statements returns the statements needed to apply the configuration.
+func (c txConfig) statements() []string {
+	var stmts []string
+	if c.snapshot != "" {
+		stmts = append(stmts, "SET TRANSACTION SNAPSHOT "+quoteLiteral(c.snapshot))
+	}
+	if c.deferrable {
+		stmts = append(stmts, "SET TRANSACTION DEFERRABLE")
+	}
+	if c.statementTimeout > 0 {
+		stmts = append(stmts, fmt.Sprintf("SET LOCAL statement_timeout = %d", ceilMillis(c.statementTimeout)))
+	}
+	if c.lockTimeout > 0 {
+		stmts = append(stmts, fmt.Sprintf("SET LOCAL lock_timeout = %d", ceilMillis(c.lockTimeout)))
+	}
+	if c.idleTimeout > 0 {
+		stmts = append(stmts, fmt.Sprintf("SET LOCAL idle_in_transaction_session_timeout = %d", ceilMillis(c.idleTimeout)))
+	}
+	if c.applicationName != "" {
+		stmts = append(stmts, "SET LOCAL application_name = "+quoteLiteral(c.applicationName))
+	}
+	return stmts
+}
+
+// ceilMillis returns d in milliseconds, rounded up so that a positive
+// duration below one millisecond does not become 0, which the server
+// takes as no timeout.
+func ceilMillis(d time.Duration) int64 {
+	ms := int64(d / time.Millisecond)
+	if d%time.Millisecond > 0 {
+		ms++
+	}
+	return ms
+}
+
+// apply issues the configuration statements using exec.
+func (c txConfig) apply(
+	ctx context.Context,
+	exec func(ctx context.Context, query string) error,
+) error {
+	for _, stmt := range c.statements() {
+		if err := exec(ctx, stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// IsStatementTimeout reports whether err is a statement cancelled by the
+// server (SQLSTATE 57014), as happens when statement_timeout is
+// exceeded. PostgreSQL reports a statement cancelled on request with the
+// same code.
+func IsStatementTimeout(err error) bool {
+	return sqlState(err) == sqlStateQueryCanceled
+}
+
+// IsLockTimeout reports whether err is a statement that gave up waiting
+// for a lock (SQLSTATE 55P03), as happens when lock_timeout is exceeded.
+func IsLockTimeout(err error) bool {
+	return sqlState(err) == sqlStateLockNotAvailable
+}
+
+// IsIdleInTransactionTimeout reports whether err reports a session that
+// the server terminated because its transaction stayed idle for longer
+// than idle_in_transaction_session_timeout (SQLSTATE 25P03), see
+// IdleInTransactionTimeout.
+func IsIdleInTransactionTimeout(err error) bool {
+	return sqlState(err) == sqlStateIdleInTransactionTimeout
+}
@@ -0,0 +1,38 @@
+package tx_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/arunni/go-db-tx/tx"
+	"github.com/arunni/go-db-tx/tx/txtest"
+)
+
+func TestTxOptionStatements(t *testing.T) {
+	tests := []struct {
+		name string
+		opt  tx.TxOption
+		want string
+	}{
+		{"statement timeout", tx.StatementTimeout(1500 * time.Millisecond), "SET LOCAL statement_timeout = 1500"},
+		{"sub-millisecond statement timeout", tx.StatementTimeout(time.Microsecond), "SET LOCAL statement_timeout = 1"},
+		{"lock timeout rounded up", tx.LockTimeout(2*time.Millisecond + time.Nanosecond), "SET LOCAL lock_timeout = 3"},
+		{"sub-millisecond idle timeout", tx.IdleInTransactionTimeout(500 * time.Microsecond), "SET LOCAL idle_in_transaction_session_timeout = 1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spy := txtest.NewSpy()
+			r := tx.NewBaseRepo(spy.DB(), nil)
+
+			err := r.WithPostgresDBTxOpts(context.Background(), nil, func(ctx context.Context) error {
+				return nil
+			}, tt.opt)
+			if err != nil {
+				t.Fatal(err)
+			}
+			assertQueries(t, spy, tt.want)
+		})
+	}
+}
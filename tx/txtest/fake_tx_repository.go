@@ -0,0 +1,142 @@
+// Package txtest provides test doubles for code built on package tx, so
+// that usecases can be unit tested without a database.
+package txtest
+
+import (
+	"context"
+	"sync"
+
+	"github.com/arunni/go-db-tx/tx"
+)
+
+// activeKey marks a context as running inside a FakeTxRepository
+// transaction of backend.
+type activeKey struct {
+	backend tx.Backend
+}
+
+// FakeTxRepository is an in-memory tx.TxRepository.
+//
+// Every With*Tx call runs fn with a context marked as transactional;
+// nested calls reuse the outer transaction of the same backend, like
+// tx.BaseRepo does, while a call for the other backend starts its own. The
+// repository records how many transactions were started, committed and
+// rolled back so tests can assert on them. The zero value is ready to
+// use and safe for concurrent use.
+type FakeTxRepository struct {
+	// BeginErr, when set, is returned by new transactions without
+	// calling fn.
+	BeginErr error
+
+	// CommitErr, when set, is returned by new transactions after fn
+	// succeeds; the transaction is recorded as rolled back.
+	CommitErr error
+
+	mu         sync.Mutex
+	started    int
+	committed  int
+	rolledBack int
+}
+
+// Compile-time assertion to ensure FakeTxRepository implements tx.TxRepository.
+var _ tx.TxRepository = (*FakeTxRepository)(nil)
+
+// WithPostgresDBTx runs fn within a fake PostgreSQL transaction.
+func (f *FakeTxRepository) WithPostgresDBTx(
+	ctx context.Context,
+	fn func(ctx context.Context) error,
+) error {
+	return f.withTx(ctx, tx.BackendPostgres, fn)
+}
+
+// WithTimescaleDBTx runs fn within a fake TimescaleDB transaction.
+func (f *FakeTxRepository) WithTimescaleDBTx(
+	ctx context.Context,
+	fn func(ctx context.Context) error,
+) error {
+	return f.withTx(ctx, tx.BackendTimescale, fn)
+}
+
+// withTx records the lifecycle of a fake transaction around fn.
+func (f *FakeTxRepository) withTx(
+	ctx context.Context,
+	backend tx.Backend,
+	fn func(ctx context.Context) error,
+) error {
+
+	// Reuse existing transaction if present
+	key := activeKey{backend: backend}
+	if ctx.Value(key) == f {
+		return fn(ctx)
+	}
+
+	if f.BeginErr != nil {
+		return f.BeginErr
+	}
+
+	f.mu.Lock()
+	f.started++
+	f.mu.Unlock()
+
+	txCtx := context.WithValue(ctx, key, f)
+
+	defer func() {
+		if p := recover(); p != nil {
+			f.record(&f.rolledBack)
+			panic(p)
+		}
+	}()
+
+	if err := fn(txCtx); err != nil {
+		f.record(&f.rolledBack)
+		return err
+	}
+
+	if f.CommitErr != nil {
+		f.record(&f.rolledBack)
+		return f.CommitErr
+	}
+
+	f.record(&f.committed)
+	return nil
+}
+
+// record increments one of the outcome counters.
+func (f *FakeTxRepository) record(counter *int) {
+	f.mu.Lock()
+	*counter++
+	f.mu.Unlock()
+}
+
+// InTx reports whether ctx carries a transaction of this repository, of
+// either backend.
+func (f *FakeTxRepository) InTx(ctx context.Context) bool {
+	return f.InBackendTx(ctx, tx.BackendPostgres) || f.InBackendTx(ctx, tx.BackendTimescale)
+}
+
+// InBackendTx reports whether ctx carries a transaction of this
+// repository for backend.
+func (f *FakeTxRepository) InBackendTx(ctx context.Context, backend tx.Backend) bool {
+	return ctx.Value(activeKey{backend: backend}) == f
+}
+
+// Started returns the number of transactions started.
+func (f *FakeTxRepository) Started() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.started
+}
+
+// Committed returns the number of transactions committed.
+func (f *FakeTxRepository) Committed() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.committed
+}
+
+// RolledBack returns the number of transactions rolled back.
+func (f *FakeTxRepository) RolledBack() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.rolledBack
+}
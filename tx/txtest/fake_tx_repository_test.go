@@ -0,0 +1,103 @@
+package txtest_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/arunni/go-db-tx/tx"
+	"github.com/arunni/go-db-tx/tx/txtest"
+)
+
+// assertCounts fails the test unless f recorded the given numbers of
+// started, committed and rolled back transactions.
+func assertCounts(t *testing.T, f *txtest.FakeTxRepository, started, committed, rolledBack int) {
+	t.Helper()
+
+	if f.Started() != started || f.Committed() != committed || f.RolledBack() != rolledBack {
+		t.Fatalf("started, committed, rolled back = %d, %d, %d, want %d, %d, %d",
+			f.Started(), f.Committed(), f.RolledBack(), started, committed, rolledBack)
+	}
+}
+
+func TestFakeTxRepositoryReusesSameBackend(t *testing.T) {
+	f := &txtest.FakeTxRepository{}
+
+	err := f.WithPostgresDBTx(context.Background(), func(ctx context.Context) error {
+		if !f.InTx(ctx) || !f.InBackendTx(ctx, tx.BackendPostgres) {
+			t.Error("fn runs outside a postgres transaction")
+		}
+		if f.InBackendTx(ctx, tx.BackendTimescale) {
+			t.Error("fn runs inside a timescale transaction")
+		}
+		return f.WithPostgresDBTx(ctx, func(ctx context.Context) error { return nil })
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertCounts(t, f, 1, 1, 0)
+}
+
+func TestFakeTxRepositorySeparatesBackends(t *testing.T) {
+	f := &txtest.FakeTxRepository{}
+
+	err := f.WithPostgresDBTx(context.Background(), func(ctx context.Context) error {
+		return f.WithTimescaleDBTx(ctx, func(ctx context.Context) error {
+			if !f.InBackendTx(ctx, tx.BackendPostgres) || !f.InBackendTx(ctx, tx.BackendTimescale) {
+				t.Error("fn does not see both transactions")
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertCounts(t, f, 2, 2, 0)
+}
+
+func TestFakeTxRepositoryOutcomes(t *testing.T) {
+	boom := errors.New("boom")
+	ctx := context.Background()
+
+	t.Run("fn error", func(t *testing.T) {
+		f := &txtest.FakeTxRepository{}
+		if err := f.WithPostgresDBTx(ctx, func(context.Context) error { return boom }); !errors.Is(err, boom) {
+			t.Fatalf("err = %v, want boom", err)
+		}
+		assertCounts(t, f, 1, 0, 1)
+	})
+
+	t.Run("begin error", func(t *testing.T) {
+		f := &txtest.FakeTxRepository{BeginErr: boom}
+		called := false
+		err := f.WithTimescaleDBTx(ctx, func(context.Context) error {
+			called = true
+			return nil
+		})
+		if !errors.Is(err, boom) || called {
+			t.Fatalf("err = %v, called = %v, want boom without calling fn", err, called)
+		}
+		assertCounts(t, f, 0, 0, 0)
+	})
+
+	t.Run("commit error", func(t *testing.T) {
+		f := &txtest.FakeTxRepository{CommitErr: boom}
+		if err := f.WithPostgresDBTx(ctx, func(context.Context) error { return nil }); !errors.Is(err, boom) {
+			t.Fatalf("err = %v, want boom", err)
+		}
+		assertCounts(t, f, 1, 0, 1)
+	})
+
+	t.Run("panic", func(t *testing.T) {
+		f := &txtest.FakeTxRepository{}
+		func() {
+			defer func() {
+				if p := recover(); p != "kaboom" {
+					t.Fatalf("recovered %v, want kaboom", p)
+				}
+			}()
+			_ = f.WithPostgresDBTx(ctx, func(context.Context) error { panic("kaboom") })
+		}()
+		assertCounts(t, f, 1, 0, 1)
+	})
+}
@@ -0,0 +1,278 @@
+package txtest
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"sync"
+)
+
+// CallKind identifies the kind of a recorded call.
+type CallKind string
+
+const (
+	CallExec     CallKind = "exec"
+	CallQuery    CallKind = "query"
+	CallBegin    CallKind = "begin"
+	CallCommit   CallKind = "commit"
+	CallRollback CallKind = "rollback"
+)
+
+// Call is a single call recorded by a Spy.
+type Call struct {
+	Kind CallKind
+
+	// Query and Args are set for exec and query calls.
+	Query string
+	Args  []any
+
+	// InTx reports whether the call ran within a transaction.
+	InTx bool
+}
+
+// stub is the canned response of a query registered on a Spy.
+type stub struct {
+	columns      []string
+	rows         [][]driver.Value
+	rowsAffected int64
+	err          error
+}
+
+// Spy is an in-memory database that records every statement issued
+// through it, for asserting which SQL a repository runs.
+//
+// DB returns a *sql.DB backed by the spy. It can be passed to
+// tx.NewBaseRepo, in which case PostgresQueryExecutor and transactions
+// work as usual and are recorded too, or used directly wherever a
+// PostgresQueryExecutor-compatible executor is expected. Statements
+// without a stub succeed with no rows and zero rows affected, so
+// QueryRowContext(...).Scan reports sql.ErrNoRows.
+type Spy struct {
+	db *sql.DB
+
+	mu    sync.Mutex
+	calls []Call
+	stubs map[string]stub
+}
+
+// NewSpy returns a new, empty Spy.
+func NewSpy() *Spy {
+	s := &Spy{stubs: make(map[string]stub)}
+	s.db = sql.OpenDB(connector{spy: s})
+	return s
+}
+
+// DB returns the *sql.DB backed by the spy.
+func (s *Spy) DB() *sql.DB {
+	return s.db
+}
+
+// StubQuery makes query return the given columns and rows.
+func (s *Spy) StubQuery(query string, columns []string, rows ...[]any) {
+	values := make([][]driver.Value, len(rows))
+	for i, row := range rows {
+		values[i] = make([]driver.Value, len(row))
+		for j, v := range row {
+			values[i][j] = v
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.stubs[query] = stub{columns: columns, rows: values}
+}
+
+// StubExec makes query report rowsAffected affected rows.
+func (s *Spy) StubExec(query string, rowsAffected int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.stubs[query] = stub{rowsAffected: rowsAffected}
+}
+
+// StubError makes query fail with err.
+func (s *Spy) StubError(query string, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.stubs[query] = stub{err: err}
+}
+
+// Calls returns a copy of all recorded calls, in order.
+func (s *Spy) Calls() []Call {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Call(nil), s.calls...)
+}
+
+// Queries returns the SQL text of all recorded exec and query calls,
+// in order.
+func (s *Spy) Queries() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var queries []string
+	for _, c := range s.calls {
+		if c.Kind == CallExec || c.Kind == CallQuery {
+			queries = append(queries, c.Query)
+		}
+	}
+	return queries
+}
+
+// Reset forgets all recorded calls. Stubs are kept.
+func (s *Spy) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.calls = nil
+}
+
+// record appends a call and returns the stub registered for its query.
+func (s *Spy) record(c Call) stub {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.calls = append(s.calls, c)
+	return s.stubs[c.Query]
+}
+
+// -----------------------------
+// database/sql Driver
+// -----------------------------
+
+// errNotSupported is returned when the spy driver is opened by name.
+var errNotSupported = errors.New("txtest: spy driver must be used through Spy.DB")
+
+type spyDriver struct{}
+
+func (spyDriver) Open(string) (driver.Conn, error) { return nil, errNotSupported }
+
+type connector struct {
+	spy *Spy
+}
+
+func (c connector) Connect(context.Context) (driver.Conn, error) { return &conn{spy: c.spy}, nil }
+func (c connector) Driver() driver.Driver                        { return spyDriver{} }
+
+type conn struct {
+	spy  *Spy
+	inTx bool
+}
+
+var (
+	_ driver.ExecerContext      = (*conn)(nil)
+	_ driver.QueryerContext     = (*conn)(nil)
+	_ driver.ConnBeginTx        = (*conn)(nil)
+	_ driver.NamedValueChecker  = (*conn)(nil)
+	_ driver.ConnPrepareContext = (*conn)(nil)
+)
+
+func (c *conn) Prepare(query string) (driver.Stmt, error) {
+	return &stmt{conn: c, query: query}, nil
+}
+
+func (c *conn) PrepareContext(_ context.Context, query string) (driver.Stmt, error) {
+	return c.Prepare(query)
+}
+
+func (c *conn) Close() error { return nil }
+
+func (c *conn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+func (c *conn) BeginTx(context.Context, driver.TxOptions) (driver.Tx, error) {
+	c.spy.record(Call{Kind: CallBegin, InTx: true})
+	c.inTx = true
+	return connTx{conn: c}, nil
+}
+
+// CheckNamedValue accepts every argument as is, so calls record the
+// values passed by the caller.
+func (c *conn) CheckNamedValue(*driver.NamedValue) error { return nil }
+
+func (c *conn) ExecContext(_ context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	st := c.spy.record(Call{Kind: CallExec, Query: query, Args: argValues(args), InTx: c.inTx})
+	if st.err != nil {
+		return nil, st.err
+	}
+	return driver.RowsAffected(st.rowsAffected), nil
+}
+
+func (c *conn) QueryContext(_ context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	st := c.spy.record(Call{Kind: CallQuery, Query: query, Args: argValues(args), InTx: c.inTx})
+	if st.err != nil {
+		return nil, st.err
+	}
+	return &rows{columns: st.columns, values: st.rows}, nil
+}
+
+// argValues converts driver arguments back to plain values.
+func argValues(args []driver.NamedValue) []any {
+	if len(args) == 0 {
+		return nil
+	}
+	values := make([]any, len(args))
+	for i, a := range args {
+		values[i] = a.Value
+	}
+	return values
+}
+
+type connTx struct {
+	conn *conn
+}
+
+func (t connTx) Commit() error {
+	t.conn.inTx = false
+	t.conn.spy.record(Call{Kind: CallCommit, InTx: true})
+	return nil
+}
+
+func (t connTx) Rollback() error {
+	t.conn.inTx = false
+	t.conn.spy.record(Call{Kind: CallRollback, InTx: true})
+	return nil
+}
+
+type stmt struct {
+	conn  *conn
+	query string
+}
+
+func (s *stmt) Close() error  { return nil }
+func (s *stmt) NumInput() int { return -1 }
+
+func (s *stmt) Exec(args []driver.Value) (driver.Result, error) {
+	return s.conn.ExecContext(context.Background(), s.query, namedValues(args))
+}
+
+func (s *stmt) Query(args []driver.Value) (driver.Rows, error) {
+	return s.conn.QueryContext(context.Background(), s.query, namedValues(args))
+}
+
+// namedValues converts positional driver values to named values.
+func namedValues(args []driver.Value) []driver.NamedValue {
+	named := make([]driver.NamedValue, len(args))
+	for i, v := range args {
+		named[i] = driver.NamedValue{Ordinal: i + 1, Value: v}
+	}
+	return named
+}
+
+type rows struct {
+	columns []string
+	values  [][]driver.Value
+	next    int
+}
+
+func (r *rows) Columns() []string { return r.columns }
+func (r *rows) Close() error      { return nil }
+
+func (r *rows) Next(dest []driver.Value) error {
+	if r.next >= len(r.values) {
+		return io.EOF
+	}
+	copy(dest, r.values[r.next])
+	r.next++
+	return nil
+}
@@ -0,0 +1,107 @@
+package txtest_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/arunni/go-db-tx/tx/txtest"
+)
+
+func TestSpyStubs(t *testing.T) {
+	spy := txtest.NewSpy()
+	spy.StubQuery("SELECT id, name FROM users", []string{"id", "name"},
+		[]any{int64(1), "ada"},
+		[]any{int64(2), "grace"},
+	)
+	spy.StubExec("DELETE FROM users", 2)
+	boom := errors.New("boom")
+	spy.StubError("DROP TABLE users", boom)
+
+	ctx := context.Background()
+	db := spy.DB()
+
+	rows, err := db.QueryContext(ctx, "SELECT id, name FROM users")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for rows.Next() {
+		var id int64
+		var name string
+		if err := rows.Scan(&id, &name); err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, name)
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"ada", "grace"}; !slices.Equal(names, want) {
+		t.Fatalf("names = %q, want %q", names, want)
+	}
+
+	res, err := db.ExecContext(ctx, "DELETE FROM users")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n, _ := res.RowsAffected(); n != 2 {
+		t.Fatalf("rows affected = %d, want 2", n)
+	}
+
+	if _, err := db.ExecContext(ctx, "DROP TABLE users"); !errors.Is(err, boom) {
+		t.Fatalf("err = %v, want boom", err)
+	}
+
+	var n int
+	if err := db.QueryRowContext(ctx, "SELECT count(*) FROM users").Scan(&n); !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("unstubbed query err = %v, want sql.ErrNoRows", err)
+	}
+}
+
+func TestSpyCalls(t *testing.T) {
+	spy := txtest.NewSpy()
+	ctx := context.Background()
+	db := spy.DB()
+
+	if _, err := db.ExecContext(ctx, "UPDATE a SET n = $1", 1); err != nil {
+		t.Fatal(err)
+	}
+	sqlTx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := sqlTx.ExecContext(ctx, "UPDATE b"); err != nil {
+		t.Fatal(err)
+	}
+	if err := sqlTx.Rollback(); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []txtest.Call{
+		{Kind: txtest.CallExec, Query: "UPDATE a SET n = $1", Args: []any{1}},
+		{Kind: txtest.CallBegin, InTx: true},
+		{Kind: txtest.CallExec, Query: "UPDATE b", InTx: true},
+		{Kind: txtest.CallRollback, InTx: true},
+	}
+	calls := spy.Calls()
+	if len(calls) != len(want) {
+		t.Fatalf("calls = %+v, want %+v", calls, want)
+	}
+	for i, c := range calls {
+		w := want[i]
+		if c.Kind != w.Kind || c.Query != w.Query || c.InTx != w.InTx || !slices.Equal(c.Args, w.Args) {
+			t.Fatalf("calls[%d] = %+v, want %+v", i, c, w)
+		}
+	}
+	if want := []string{"UPDATE a SET n = $1", "UPDATE b"}; !slices.Equal(spy.Queries(), want) {
+		t.Fatalf("queries = %q, want %q", spy.Queries(), want)
+	}
+
+	spy.Reset()
+	if calls := spy.Calls(); len(calls) != 0 {
+		t.Fatalf("calls after Reset = %+v, want none", calls)
+	}
+}
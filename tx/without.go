@@ -0,0 +1,46 @@
+package tx
+
+import "context"
+
+// -----------------------------
+// Running Outside Transactions
+// -----------------------------
+
+// WithoutPostgresTx executes the given function with the PostgreSQL
+// transaction removed from the context.
+//
+// Inside fn, PostgresQueryExecutor returns the base *sql.DB and helpers
+// such as WithPostgresDBTx start a new, independent transaction. This
+// breaks atomicity by design: statements run inside fn are committed on
+// their own and survive a rollback of the surrounding transaction. It is
+// meant for statements that must not or cannot run in a transaction, such
+// as maintenance queries. Transactions of other backends in the context
+// are left in place, but, as with WithPostgresDBTxNew, fn no longer sees
+// the state of the surrounding transaction: callbacks cannot be
+// registered on it, and TxStore and TxStartedAt report no transaction.
+func (r *BaseRepo) WithoutPostgresTx(
+	ctx context.Context,
+	fn func(ctx context.Context) error,
+) error {
+	return fn(withoutTx(ctx, r.keys.postgres))
+}
+
+// WithoutTimescaleTx executes the given function with the TimescaleDB
+// transaction removed from the context.
+//
+// Inside fn, TimescaleQueryExecutor returns the base *pgxpool.Pool.
+// Like WithoutPostgresTx, this breaks atomicity by design: statements
+// run inside fn are not undone if the surrounding transaction rolls back.
+func (r *BaseRepo) WithoutTimescaleTx(
+	ctx context.Context,
+	fn func(ctx context.Context) error,
+) error {
+	return fn(withoutTx(ctx, r.keys.timescale))
+}
+
+// withoutTx removes the transaction stored under key and its scope from
+// ctx, and marks the removal as deliberate for StrictContextMode.
+func withoutTx(ctx context.Context, key contextKey) context.Context {
+	ctx = detachTx(ctx, key)
+	return context.WithValue(ctx, detachedKey(key), true)
+}
@@ -0,0 +1,49 @@
+package tx_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/arunni/go-db-tx/tx"
+	"github.com/arunni/go-db-tx/tx/txtest"
+)
+
+func TestWithoutPostgresTx(t *testing.T) {
+	spy := txtest.NewSpy()
+	r := tx.NewBaseRepo(spy.DB(), nil)
+
+	err := r.WithPostgresDBTx(context.Background(), func(ctx context.Context) error {
+		store, _ := tx.TxStore(ctx)
+		store.Store("outer", true)
+
+		return r.WithoutPostgresTx(ctx, func(ctx context.Context) error {
+			if r.IsInPostgresTx(ctx) {
+				t.Error("IsInPostgresTx = true without the transaction")
+			}
+			if err := tx.RegisterAfterCommit(ctx, func() {}); !errors.Is(err, tx.ErrNoTransaction) {
+				t.Errorf("RegisterAfterCommit err = %v, want ErrNoTransaction", err)
+			}
+			if _, err := r.PostgresQueryExecutor(ctx).ExecContext(ctx, "VACUUM"); err != nil {
+				return err
+			}
+
+			return r.WithPostgresDBTx(ctx, func(ctx context.Context) error {
+				store, _ := tx.TxStore(ctx)
+				if _, ok := store.Load("outer"); ok {
+					t.Error("new transaction shares the store of the surrounding one")
+				}
+				return nil
+			})
+		})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	calls := spy.Calls()
+	if len(calls) != 5 || calls[1].Query != "VACUUM" || calls[1].InTx {
+		t.Fatalf("calls = %+v, want VACUUM outside the transaction", calls)
+	}
+	assertKinds(t, spy, txtest.CallBegin, txtest.CallExec, txtest.CallBegin, txtest.CallCommit, txtest.CallCommit)
+}